@@ -0,0 +1,129 @@
+package dogrus_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+	"github.com/sirupsen/logrus"
+)
+
+// stalledSink is a Sink holding the first batch until release is closed,
+// recording the messages and sample rates of the entries it receives.
+type stalledSink struct {
+	entered chan struct{}
+	release chan struct{}
+	once    sync.Once
+
+	mu    sync.Mutex
+	rates map[string]interface{}
+}
+
+func newStalledSink() *stalledSink {
+	return &stalledSink{
+		entered: make(chan struct{}),
+		release: make(chan struct{}),
+		rates:   make(map[string]interface{}),
+	}
+}
+
+func (s *stalledSink) Send(ctx context.Context, batch [][]byte) error {
+	s.once.Do(func() { close(s.entered) })
+	<-s.release
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, data := range batch {
+		var e map[string]interface{}
+		if err := json.Unmarshal(data, &e); err != nil {
+			return err
+		}
+		s.rates[e["message"].(string)] = e[dogrus.SampleRateKey]
+	}
+
+	return nil
+}
+
+func (s *stalledSink) rate(message string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rate, ok := s.rates[message]
+	return rate, ok
+}
+
+func TestAdaptiveSamplingUnderPressure(t *testing.T) {
+	clock := dogrustest.NewClock(time.Now())
+	sink := newStalledSink()
+
+	d := dogrus.New("key", dogrus.Opts{
+		Clock:          clock,
+		Sink:           sink,
+		QueueSize:      10,
+		MaxBatchSize:   1,
+		OverflowPolicy: dogrus.OverflowDropNewest,
+		AdaptiveSampling: &dogrus.AdaptiveSampling{
+			TargetRate:    1000,
+			MinSampleRate: 0.5,
+			Window:        time.Second,
+		},
+	})
+	defer d.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.AddHook(d)
+
+	// the first entry is stuck in the sink, the next ones fill 90% of the
+	// queue
+	logger.Warn("stuck")
+	<-sink.entered
+	for i := 0; i < 9; i++ {
+		logger.Warn("queued")
+	}
+	if depth := d.Client().Health().QueueDepth; depth != 9 {
+		t.Fatalf("QueueDepth is %d, want 9", depth)
+	}
+
+	// the rate is recomputed at the end of the window: the queue usage
+	// would bring it to 0.2, MinSampleRate keeps it at 0.5
+	clock.Advance(time.Second)
+	for i := 0; i < 50; i++ {
+		logger.Info("sampled")
+	}
+	if rate := d.Stats().AdaptiveSampleRate; rate != 0.5 {
+		t.Fatalf("AdaptiveSampleRate is %v under pressure, want 0.5", rate)
+	}
+
+	close(sink.release)
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rate, ok := sink.rate("sampled"); !ok || rate != 0.5 {
+		t.Errorf("sampled entry has rate %v, want 0.5", rate)
+	}
+	if rate, ok := sink.rate("queued"); !ok || rate != nil {
+		t.Errorf("warning has rate %v, want none", rate)
+	}
+
+	// the queue is empty, sampling stops at the next window
+	clock.Advance(time.Second)
+	logger.Info("recovered")
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rate := d.Stats().AdaptiveSampleRate; rate != 1 {
+		t.Errorf("AdaptiveSampleRate is %v after the pressure dropped, want 1", rate)
+	}
+	if rate, ok := sink.rate("recovered"); !ok || rate != nil {
+		t.Errorf("entry after recovery is missing or has rate %v", rate)
+	}
+}
@@ -0,0 +1,47 @@
+package dogrus
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// SetAPIKey replaces the API key used by the next requests, to rotate it
+// without recreating the client. It has no effect when Opts.APIKeyFunc is
+// set.
+func (c *Client) SetAPIKey(apiKey string) {
+	c.keyMu.Lock()
+	defer c.keyMu.Unlock()
+
+	c.key = apiKey
+}
+
+// SetAPIKey replaces the API key used by the hook Client, see
+// Client.SetAPIKey.
+func (d *Hook) SetAPIKey(apiKey string) {
+	d.client.SetAPIKey(apiKey)
+}
+
+// apiKey returns the API key for the next request.
+func (c *Client) apiKey() (string, error) {
+	if c.opts.APIKeyFunc != nil {
+		return c.opts.APIKeyFunc()
+	}
+
+	c.keyMu.RLock()
+	defer c.keyMu.RUnlock()
+
+	return c.key, nil
+}
+
+// redactKey removes key from the URL reported by the *url.Error returned by
+// http.Client.Do, since with Opts.APIKeyInPath the error would leak it to
+// OnError, the dead letters and the debug log.
+func redactKey(err error, key string) error {
+	var urlErr *url.Error
+	if key != "" && errors.As(err, &urlErr) {
+		urlErr.URL = strings.ReplaceAll(urlErr.URL, key, "<api key>")
+	}
+
+	return err
+}
@@ -0,0 +1,49 @@
+package dogrus
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyInPathRedactedFromErrors(t *testing.T) {
+	const key = "0123456789abcdef"
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	var (
+		debug    syncBuffer
+		reported []string
+	)
+	c := NewClient(key, Opts{
+		PostURL:      "http://" + addr + "/v1/input",
+		APIKeyInPath: true,
+		DebugLogger:  &debug,
+		OnError: func(err error, _ [][]byte) {
+			reported = append(reported, err.Error())
+		},
+	})
+
+	c.Enqueue([]byte(`{"message":"hello"}`))
+	if err := c.Close(); err == nil || strings.Contains(err.Error(), key) {
+		t.Fatalf("Close returned %v", err)
+	}
+
+	if len(reported) != 1 {
+		t.Fatalf("reported %d errors, want 1", len(reported))
+	}
+	if strings.Contains(reported[0], key) {
+		t.Errorf("OnError received the API key: %s", reported[0])
+	}
+	if !strings.Contains(reported[0], addr+"/v1/input") {
+		t.Errorf("OnError lost the address of the intake: %s", reported[0])
+	}
+	if out := debug.String(); out == "" || strings.Contains(out, key) {
+		t.Errorf("the debug log holds the API key: %s", out)
+	}
+}
@@ -0,0 +1,60 @@
+package dogrus
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// BeforeSend is a step of Opts.BeforeSend, called with every HTTP request
+// right before it's made, for example to sign or encrypt it, or to keep an
+// audit copy. It returns the body to send instead of body, which is the
+// encoded batch as returned by the previous step, compressed when Compress
+// is enabled. The body is a copy the step can modify or retain.
+// It can also change the headers and URL of req, but not its body.
+// It's called again for every retry. Returning an error fails the batch
+// without retrying it.
+type BeforeSend func(req *http.Request, body []byte) ([]byte, error)
+
+// beforeSendError is returned when a BeforeSend step fails, so the batch
+// isn't retried.
+type beforeSendError struct {
+	err error
+}
+
+func (e *beforeSendError) Error() string {
+	return "dogrus: before send: " + e.err.Error()
+}
+
+func (e *beforeSendError) Unwrap() error {
+	return e.err
+}
+
+// beforeSend runs req through the steps of Opts.BeforeSend, replacing its
+// body with the one they return.
+func (c *Client) beforeSend(req *http.Request, body *payload) error {
+	buf := bytes.NewBuffer(make([]byte, 0, body.Len()))
+	r := body.body()
+	_, err := buf.ReadFrom(r)
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	for _, step := range c.opts.BeforeSend {
+		data, err = step(req, data)
+		if err != nil {
+			return &beforeSendError{err: err}
+		}
+	}
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.ContentLength = int64(len(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package dogrus
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is reported for batches that are not sent because the
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("dogrus: circuit breaker is open")
+
+// CircuitState is the state of the circuit breaker protecting the intake
+// endpoint.
+type CircuitState int
+
+const (
+	// CircuitClosed means requests are sent normally.
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen means too many requests failed in a row, so batches are
+	// discarded without even trying until the cool-down period expires.
+	CircuitOpen
+
+	// CircuitHalfOpen means the cool-down period expired and the next
+	// request is a probe: if it succeeds the circuit is closed again,
+	// otherwise it's opened for another cool-down period. Batches sent
+	// while the probe is in flight are discarded like with CircuitOpen.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so the state is readable in
+// Health and expvar output.
+func (s CircuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// breaker is a circuit breaker counting consecutive failures.
+type breaker struct {
+	clock     Clock
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     CircuitState
+	openedAt  time.Time
+	probing   bool // a probe is in flight while half-open
+}
+
+// allow tells if a request can be made. While half-open only one request is
+// allowed at a time, the probe, until its outcome is known.
+func (b *breaker) allow() bool {
+	if b.threshold < 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && b.clock.Now().Sub(b.openedAt) >= b.cooldown {
+		b.state = CircuitHalfOpen
+	}
+
+	switch b.state {
+	case CircuitOpen:
+		return false
+	case CircuitHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+
+	return true
+}
+
+// done ends a request allowed by allow. A probe ending without success or
+// failure being recorded, because the batch couldn't even be sent, lets the
+// next request be the probe.
+func (b *breaker) done() {
+	if b.threshold < 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// success records a successful request, closing the circuit.
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.state = CircuitClosed
+	b.probing = false
+}
+
+// failure records a failed request, opening the circuit if it was a probe
+// or if there have been too many failures in a row.
+func (b *breaker) failure() {
+	if b.threshold < 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = b.clock.Now()
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *breaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
@@ -0,0 +1,63 @@
+package dogrus
+
+import (
+	"testing"
+	"time"
+)
+
+// fixedClock is a Clock whose Now is set by the test.
+type fixedClock struct {
+	Clock
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	clock := &fixedClock{Clock: SystemClock, now: time.Now()}
+	b := &breaker{clock: clock, threshold: 1, cooldown: time.Minute}
+
+	b.failure()
+	if b.allow() {
+		t.Fatal("allowed a request while open")
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("no probe allowed after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("allowed a second request while the probe is in flight")
+	}
+	if b.State() != CircuitHalfOpen {
+		t.Fatalf("circuit is %s, want half-open", b.State())
+	}
+
+	// a probe ending without an outcome lets another one through
+	b.done()
+	if !b.allow() {
+		t.Fatal("no probe allowed after the first one was abandoned")
+	}
+
+	b.failure()
+	b.done()
+	if b.allow() || b.State() != CircuitOpen {
+		t.Fatal("the circuit isn't open again after the probe failed")
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("no probe allowed after the second cooldown")
+	}
+	b.success()
+	b.done()
+
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Fatal("request not allowed after the probe succeeded")
+		}
+		b.done()
+	}
+}
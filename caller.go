@@ -0,0 +1,56 @@
+package dogrus
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Datadog standard attributes describing where an entry was logged.
+const (
+	LoggerNameKey       = "logger.name"
+	LoggerMethodNameKey = "logger.method_name"
+	LoggerFileKey       = "logger.file"
+	LoggerLineKey       = "logger.line"
+)
+
+// hasCaller tells if the caller attributes can be added to entry.
+func (d *Hook) hasCaller(entry *logrus.Entry) bool {
+	return !d.opts.DisableCallerAttributes && entry.HasCaller()
+}
+
+// mapCaller replaces the caller reported by logrus with the Datadog logger
+// attributes. The caller is removed from e so the formatter doesn't add its
+// own func and file keys.
+func (d *Hook) mapCaller(e *logrus.Entry) {
+	if !d.hasCaller(e) {
+		return
+	}
+
+	pkg, method := SplitFunction(e.Caller.Function)
+
+	setDefault(e, LoggerNameKey, pkg)
+	setDefault(e, LoggerMethodNameKey, method)
+	setDefault(e, LoggerFileKey, e.Caller.File)
+	setDefault(e, LoggerLineKey, e.Caller.Line)
+
+	e.Caller = nil
+}
+
+// SplitFunction splits a fully qualified function name, as reported by
+// runtime.Frame, into its package path and the function name within the
+// package, which includes the receiver for methods. They are used as
+// LoggerNameKey and LoggerMethodNameKey.
+// For example github.com/a/b.(*T).M becomes github.com/a/b and (*T).M.
+func SplitFunction(function string) (string, string) {
+	slash := strings.LastIndex(function, "/")
+
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return function, ""
+	}
+
+	dot += slash + 1
+
+	return function[:dot], function[dot+1:]
+}
@@ -0,0 +1,461 @@
+package dogrus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned when using a Client or a Hook that has been closed.
+var ErrClosed = errors.New("dogrus: client is closed")
+
+// StatusError is returned when Datadog server replies with a non-2xx status
+// code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("dogrus: unexpected response status %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// record is a formatted entry waiting to be sent.
+type record struct {
+	data []byte
+
+	// spoolID identifies the copy of the entry in Opts.Spool, zero if there
+	// isn't one
+	spoolID uint64
+}
+
+// Client sends arbitrary JSON records to Datadog: they are queued, batched
+// and delivered in background, with retries. It's the engine behind Hook,
+// and can be used directly by code that doesn't log through logrus.
+// Use NewClient() to create and initialize a new client.
+type Client struct {
+	// counters are accessed atomically, keep them first for 64-bit alignment
+	enqueued      uint64
+	sent          uint64
+	dropped       uint64
+	failedFlushes uint64
+	retries       uint64
+	lastFlush     int64
+	queuedBytes   int64
+	heldBytes     int64 // queued, batched or being sent
+	failures      int64 // consecutive
+	retained      int64 // bytes of the batches being retried
+	unreachable   int64 // consecutive network failures
+	period        int64 // FlushPeriod, changed by SetFlushPeriod
+	batchSize     int64 // MaxBatchSize, changed by SetMaxBatchSize
+	pressured     int32
+
+	keyMu    sync.RWMutex
+	key      string
+	opts     Opts
+	tags     string
+	metadata map[string]string
+	postURL  string
+	created  time.Time
+	debug    *log.Logger
+	ctx      context.Context
+	cancel   context.CancelFunc
+	client   *http.Client
+	connMu   sync.Mutex
+	conn     net.Conn
+	breaker  *breaker
+	limiter  *rateLimiter
+	backlog  *backlog
+
+	fallbackMu sync.Mutex
+
+	deadLettersMu sync.Mutex
+	deadLetters   []DeadLetter
+
+	reconfigured chan struct{}
+	urgent       chan struct{}
+	room         chan struct{}
+
+	// enqueueMu is held for reading while an entry is being enqueued, so
+	// that closing can wait for it to be in the queue before draining
+	enqueueMu sync.RWMutex
+	queue     chan record
+	jobs      chan job
+	inflight  sync.WaitGroup
+	flushes   chan chan error
+	closing   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+	done      chan struct{}
+}
+
+// NewClient creates a new Client using the API key provided.
+// It's configured by the same Opts of New, but the options about logrus
+// entries, such as Formatter or Levels, are ignored.
+func NewClient(apiKey string, opts Opts) *Client {
+	opts = withDefaults(opts)
+
+	ctx, cancel := context.WithCancel(opts.Context)
+
+	c := &Client{
+		key:     apiKey,
+		opts:    opts,
+		created: opts.Clock.Now(),
+		debug:   newDebugLogger(opts),
+		tags:    ddtags(opts),
+		ctx:     ctx,
+		cancel:  cancel,
+		client:  opts.HTTPClient,
+		breaker: &breaker{
+			clock:     opts.Clock,
+			threshold: opts.BreakerThreshold,
+			cooldown:  opts.BreakerCooldown,
+		},
+		period:       int64(opts.FlushPeriod),
+		batchSize:    int64(opts.MaxBatchSize),
+		reconfigured: make(chan struct{}, 1),
+		urgent:       make(chan struct{}, 1),
+		room:         make(chan struct{}, 1),
+		queue:        make(chan record, opts.QueueSize),
+		flushes:      make(chan chan error),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	c.metadata = metadata(opts, c.tags)
+
+	c.postURL = opts.PostURL
+	if opts.BodyFormat == PlainText {
+		c.postURL = plainURL(opts.PostURL, c.metadata)
+	}
+
+	if opts.RateLimit.RequestsPerSecond > 0 || opts.RateLimit.BytesPerSecond > 0 {
+		c.limiter = newRateLimiter(opts.RateLimit, opts.Clock)
+	}
+
+	if opts.Offline != nil {
+		c.backlog = &backlog{}
+	}
+
+	if opts.Concurrency > 1 {
+		c.jobs = make(chan job)
+		for i := 0; i < opts.Concurrency; i++ {
+			go c.work()
+		}
+	}
+
+	if opts.WarmUp {
+		go c.warmUp()
+	}
+
+	go c.run(c.recovered())
+
+	return c
+}
+
+// Enqueue puts a record, a JSON object, in the queue to be sent to Datadog.
+// Records larger than MaxEntryBytes are rejected, and so are records
+// enqueued once Close is called, with ErrClosed: the ones accepted are
+// always sent by Close.
+// The record must not be modified after calling Enqueue.
+func (c *Client) Enqueue(entry []byte) error {
+	if len(entry) > c.opts.MaxEntryBytes {
+		return c.oversized(entry)
+	}
+
+	return c.enqueue(entry)
+}
+
+// Flush sends every queued log entry to Datadog server, waiting for the
+// requests to complete.
+func (c *Client) Flush() error {
+	return c.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush, but stops waiting when ctx is done, returning
+// a *DeadlineError. The entries not delivered yet are still sent in
+// background.
+func (c *Client) FlushContext(ctx context.Context) error {
+	sent := atomic.LoadUint64(&c.sent)
+	reply := make(chan error, 1)
+
+	select {
+	case c.flushes <- reply:
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return c.deadline(ctx, sent)
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return c.deadline(ctx, sent)
+	}
+}
+
+// Close flushes the pending entries and stops the client. Any request still
+// in flight after that is cancelled.
+// The client must not be used after Close.
+func (c *Client) Close() error {
+	return c.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but when ctx is done it cancels the requests
+// in flight, returning a *DeadlineError. The entries that couldn't be
+// delivered are reported like failed batches, to OnError, Fallback and
+// OnDeadLetter, and so are the ones in the backlog of Opts.Offline.
+func (c *Client) CloseContext(ctx context.Context) error {
+	sent := atomic.LoadUint64(&c.sent)
+
+	c.closeOnce.Do(func() {
+		close(c.closing)
+	})
+
+	var err error
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		err = c.deadline(ctx, sent)
+		c.cancel()
+		<-c.done
+	}
+
+	c.cancel()
+	c.closeBacklog()
+	c.closeConn()
+
+	if err != nil {
+		return err
+	}
+
+	return c.closeErr
+}
+
+// DeadlineError is returned by FlushContext and CloseContext when the
+// context is done before every entry is delivered.
+type DeadlineError struct {
+	// Err is the error of the context.
+	Err error
+
+	// Sent is the number of entries delivered while waiting.
+	Sent uint64
+
+	// Pending is the number of entries still in the queue, not counting
+	// the batch being sent.
+	Pending int
+}
+
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("dogrus: %v with %d entries pending, %d sent", e.Err, e.Pending, e.Sent)
+}
+
+func (e *DeadlineError) Unwrap() error {
+	return e.Err
+}
+
+// deadline returns the DeadlineError of ctx, given the number of entries
+// sent when the caller started waiting.
+func (c *Client) deadline(ctx context.Context, sent uint64) error {
+	return &DeadlineError{
+		Err:     ctx.Err(),
+		Sent:    atomic.LoadUint64(&c.sent) - sent,
+		Pending: len(c.queue),
+	}
+}
+
+// run is the sender loop: it collects entries from the queue into batches
+// and sends them when they are big enough or when FlushPeriod expires.
+// Entries recovered from the spool are sent first.
+// The queue is a buffered channel, a ring buffer allocated once, and so is
+// the batch array unless batches are handed to workers.
+func (c *Client) run(recovered []record) {
+	defer close(c.done)
+
+	if len(recovered) > 0 {
+		c.debugf("sending %d entries recovered from the spool", len(recovered))
+	}
+
+	for _, rec := range recovered {
+		atomic.AddInt64(&c.heldBytes, int64(len(rec.data)))
+	}
+
+	for len(recovered) > 0 {
+		n := c.maxBatchSize()
+		if n > len(recovered) {
+			n = len(recovered)
+		}
+
+		c.dispatch(recovered[:n], nil)
+		recovered = recovered[n:]
+	}
+
+	timer := c.opts.Clock.NewTimer(c.flushPeriod())
+	defer timer.Stop()
+
+	batch := make([]record, 0, c.maxBatchSize())
+	batchBytes := 0
+
+	for {
+		select {
+		case rec := <-c.queue:
+			c.dequeued(rec)
+			batch = append(batch, rec)
+			batchBytes += len(rec.data) + 1
+			if len(batch) < c.maxBatchSize() && batchBytes < c.opts.MaxBatchBytes {
+				continue
+			}
+
+			c.debugf("flushing %d entries, %d bytes: batch is full", len(batch), batchBytes)
+			c.dispatch(batch, nil)
+
+		case <-timer.C():
+			if len(batch) > 0 {
+				c.debugf("flushing %d entries: flush period expired", len(batch))
+			}
+			c.dispatch(batch, nil)
+
+		case <-c.urgent:
+			// the priority record may still be in the queue
+			for n := len(c.queue); n > 0; n-- {
+				rec := <-c.queue
+				c.dequeued(rec)
+				batch = append(batch, rec)
+			}
+
+			c.debugf("flushing %d entries: priority entry", len(batch))
+			c.dispatch(batch, nil)
+
+		case <-c.reconfigured:
+			if len(batch) < c.maxBatchSize() {
+				timer.Reset(c.flushPeriod())
+				continue
+			}
+
+			c.debugf("flushing %d entries: MaxBatchSize lowered", len(batch))
+			c.dispatch(batch, nil)
+
+		case reply := <-c.flushes:
+			c.debugf("flushing %d entries and %d queued: Flush called", len(batch), len(c.queue))
+			reply <- c.drain(batch)
+
+		case <-c.closing:
+			// writers see closing and stop, wait for the ones already past
+			// the check
+			c.enqueueMu.Lock()
+			c.enqueueMu.Unlock()
+
+			c.debugf("flushing %d entries and %d queued: closing", len(batch), len(c.queue))
+			c.closeErr = c.drain(batch)
+			if c.jobs != nil {
+				close(c.jobs)
+			}
+			return
+		}
+
+		batch = c.next(batch)
+		batchBytes = 0
+		timer.Reset(c.flushPeriod())
+	}
+}
+
+// flushPeriod returns how long to wait for the next periodic flush,
+// FlushPeriod randomized by FlushJitter.
+func (c *Client) flushPeriod() time.Duration {
+	period := time.Duration(atomic.LoadInt64(&c.period))
+	if c.opts.FlushJitter <= 0 {
+		return period
+	}
+
+	jitter := math.Min(c.opts.FlushJitter, 1) * (2*rand.Float64() - 1)
+
+	return period + time.Duration(jitter*float64(period))
+}
+
+// drain sends batch along with every entry currently in the queue, in
+// batches of at most MaxBatchSize entries, and waits for every batch in
+// flight.
+func (c *Client) drain(batch []record) error {
+	var (
+		mu  sync.Mutex
+		err error
+	)
+
+	done := func(e error) {
+		if e != nil {
+			mu.Lock()
+			err = e
+			mu.Unlock()
+		}
+	}
+
+	for n := len(c.queue); n > 0; n-- {
+		rec := <-c.queue
+		c.dequeued(rec)
+		batch = append(batch, rec)
+		if len(batch) < c.maxBatchSize() {
+			continue
+		}
+
+		c.dispatch(batch, done)
+		batch = c.next(batch)
+	}
+
+	c.dispatch(batch, done)
+	c.inflight.Wait()
+
+	return err
+}
+
+// job is a batch to be sent by a worker, done is called with the outcome
+// if not nil.
+type job struct {
+	batch []record
+	done  func(error)
+}
+
+// dispatch sends batch, handing it to a worker when Concurrency allows
+// more than one request at a time.
+func (c *Client) dispatch(batch []record, done func(error)) {
+	if c.jobs == nil || len(batch) == 0 {
+		err := c.send(batch)
+		if done != nil {
+			done(err)
+		}
+		return
+	}
+
+	c.inflight.Add(1)
+	c.jobs <- job{batch: batch, done: done}
+}
+
+// next returns the batch to fill after batch has been dispatched. Workers
+// keep the batches they are sending, so the array can only be reused when
+// there are none.
+func (c *Client) next(batch []record) []record {
+	if c.jobs == nil || len(batch) == 0 {
+		return batch[:0]
+	}
+
+	return make([]record, 0, c.maxBatchSize())
+}
+
+// work is the loop of a worker, sending the batches handed by the sender
+// loop until the client is closed.
+func (c *Client) work() {
+	for j := range c.jobs {
+		err := c.send(j.batch)
+		if j.done != nil {
+			j.done(err)
+		}
+		c.inflight.Done()
+	}
+}
@@ -0,0 +1,77 @@
+package dogrus
+
+import "time"
+
+// Clock is the source of time of a Client or a Hook: flush scheduling,
+// retry backoffs, rate limits, the circuit breaker cool-down and dedup
+// windows all go through it, so tests can drive them deterministically, see
+// the dogrustest subpackage.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a Timer sending the current time on its channel
+	// after d.
+	NewTimer(d time.Duration) Timer
+
+	// AfterFunc calls f in its own goroutine after d. The Timer it returns
+	// has no channel.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer created by a Clock, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, like time.Timer.Stop.
+	Stop() bool
+
+	// Reset changes the timer to expire after d, like time.Timer.Reset.
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the Clock backed by the time package, used by default.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// Clock returns the Opts.Clock of the client. Adapters of other logging
+// libraries timestamping their records can use it, so that the clock given
+// by tests drives them too.
+func (c *Client) Clock() Clock {
+	return c.opts.Clock
+}
+
+// sleep waits for d on clock, returning false if done is closed first.
+func sleep(clock Clock, d time.Duration, done <-chan struct{}) bool {
+	t := clock.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C():
+		return true
+	case <-done:
+		return false
+	}
+}
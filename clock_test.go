@@ -0,0 +1,272 @@
+package dogrus_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+)
+
+// waitFor polls cond until it's true, failing the test after a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitTimers waits until n timers are waiting on clock.
+func waitTimers(t *testing.T, clock *dogrustest.Clock, n int) {
+	t.Helper()
+
+	waitFor(t, "the timers", func() bool { return clock.Timers() == n })
+}
+
+func newClockClient(t *testing.T, clock *dogrustest.Clock, intake *dogrustest.Intake, opts dogrus.Opts) *dogrus.Client {
+	t.Helper()
+
+	opts.Clock = clock
+	opts.PostURL = intake.URL
+
+	c := dogrus.NewClient("key", opts)
+	t.Cleanup(func() { c.Close() })
+
+	// the flush timer of the sender loop
+	waitTimers(t, clock, 1)
+
+	return c
+}
+
+func TestClockPeriodicFlush(t *testing.T) {
+	clock := dogrustest.NewClock(time.Now())
+	intake := dogrustest.NewIntake(t, "key")
+	c := newClockClient(t, clock, intake, dogrus.Opts{FlushPeriod: 10 * time.Second})
+
+	c.Enqueue([]byte(`{"message":"hello"}`))
+	waitFor(t, "the entry to be batched", func() bool { return c.Stats().QueueDepth == 0 })
+
+	clock.Advance(10*time.Second - time.Nanosecond)
+	if clock.Timers() != 1 || len(intake.Batches()) != 0 {
+		t.Fatal("flushed before FlushPeriod")
+	}
+
+	clock.Advance(time.Nanosecond)
+	intake.WaitForEntries(t, 1, 5*time.Second)
+	intake.AssertEntry(t, "message", "hello")
+}
+
+func TestClockFlushJitter(t *testing.T) {
+	clock := dogrustest.NewClock(time.Now())
+	intake := dogrustest.NewIntake(t, "key")
+	c := newClockClient(t, clock, intake, dogrus.Opts{
+		FlushPeriod: 10 * time.Second,
+		FlushJitter: 0.5,
+	})
+
+	for i := 1; i <= 5; i++ {
+		c.Enqueue([]byte(`{"message":"hello"}`))
+		waitFor(t, "the entry to be batched", func() bool { return c.Stats().QueueDepth == 0 })
+
+		// the period is between 5 and 15 seconds
+		clock.Advance(5*time.Second - time.Millisecond)
+		if clock.Timers() != 1 || len(intake.Entries()) != i-1 {
+			t.Fatalf("round %d: flushed before FlushPeriod minus FlushJitter", i)
+		}
+
+		clock.Advance(10 * time.Second)
+		intake.WaitForEntries(t, i, 5*time.Second)
+		waitTimers(t, clock, 1)
+	}
+}
+
+func TestClockRetryBackoff(t *testing.T) {
+	clock := dogrustest.NewClock(time.Now())
+	intake := dogrustest.NewIntake(t, "key")
+	c := newClockClient(t, clock, intake, dogrus.Opts{
+		FlushPeriod:  time.Hour,
+		MaxBatchSize: 1,
+		MaxRetries:   3,
+		RetryBackoff: time.Second,
+	})
+
+	intake.Fail(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	c.Enqueue([]byte(`{"message":"hello"}`))
+
+	// the flush timer and the backoff
+	waitTimers(t, clock, 2)
+	clock.Advance(time.Second - time.Nanosecond)
+	if c.Stats().Retries != 0 {
+		t.Fatal("retried before RetryBackoff")
+	}
+	clock.Advance(time.Nanosecond)
+	waitFor(t, "the first retry", func() bool { return c.Stats().Retries == 1 })
+
+	// the backoff doubles
+	waitTimers(t, clock, 2)
+	clock.Advance(2*time.Second - time.Nanosecond)
+	if clock.Timers() != 2 || c.Stats().Retries != 1 {
+		t.Fatal("retried before twice RetryBackoff")
+	}
+	clock.Advance(time.Nanosecond)
+
+	intake.WaitForEntries(t, 1, 5*time.Second)
+	if s := c.Stats(); s.Retries != 2 || s.FailedFlushes != 0 {
+		t.Fatalf("Retries %d FailedFlushes %d, want 2 and 0", s.Retries, s.FailedFlushes)
+	}
+}
+
+func TestClockRetryFor(t *testing.T) {
+	failed := make(chan error, 1)
+
+	clock := dogrustest.NewClock(time.Now())
+	intake := dogrustest.NewIntake(t, "key")
+	c := newClockClient(t, clock, intake, dogrus.Opts{
+		FlushPeriod:  time.Hour,
+		MaxBatchSize: 1,
+		RetryFor:     3 * time.Second,
+		RetryBackoff: time.Second,
+		OnError:      func(err error, _ [][]byte) { failed <- err },
+	})
+
+	statuses := make([]int, 10)
+	for i := range statuses {
+		statuses[i] = http.StatusTooManyRequests
+	}
+	intake.Fail(statuses...)
+	c.Enqueue([]byte(`{"message":"hello"}`))
+
+	// tried at 0, 1 and 3 seconds, the next backoff would exceed RetryFor
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second} {
+		waitTimers(t, clock, 2)
+		clock.Advance(backoff)
+	}
+
+	select {
+	case err := <-failed:
+		var statusErr *dogrus.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+			t.Fatalf("reported %v, want a 429 StatusError", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch is still retried past RetryFor")
+	}
+
+	if s := c.Stats(); s.Retries != 2 || s.FailedFlushes != 1 {
+		t.Fatalf("Retries %d FailedFlushes %d, want 2 and 1", s.Retries, s.FailedFlushes)
+	}
+}
+
+func TestClockBreakerCooldown(t *testing.T) {
+	failed := make(chan error, 10)
+
+	clock := dogrustest.NewClock(time.Now())
+	intake := dogrustest.NewIntake(t, "key")
+	c := newClockClient(t, clock, intake, dogrus.Opts{
+		FlushPeriod:      time.Hour,
+		MaxBatchSize:     1,
+		BreakerThreshold: 1,
+		BreakerCooldown:  30 * time.Second,
+		OnError:          func(err error, _ [][]byte) { failed <- err },
+	})
+
+	intake.Fail(http.StatusInternalServerError)
+	c.Enqueue([]byte(`{"message":"failed"}`))
+	<-failed
+
+	if state := c.Health().Circuit; state != dogrus.CircuitOpen {
+		t.Fatalf("circuit is %s, want open", state)
+	}
+
+	clock.Advance(30*time.Second - time.Nanosecond)
+	c.Enqueue([]byte(`{"message":"short-circuited"}`))
+	if err := <-failed; err != dogrus.ErrCircuitOpen {
+		t.Fatalf("reported %v, want ErrCircuitOpen", err)
+	}
+
+	clock.Advance(time.Nanosecond)
+	c.Enqueue([]byte(`{"message":"probe"}`))
+	intake.WaitForEntries(t, 1, 5*time.Second)
+	intake.AssertEntry(t, "message", "probe")
+
+	waitFor(t, "the circuit to close", func() bool { return c.Health().Circuit == dogrus.CircuitClosed })
+}
+
+// unreachable is a transport failing to dial while down is set.
+type unreachable struct {
+	down int32
+}
+
+func (u *unreachable) RoundTrip(req *http.Request) (*http.Response, error) {
+	if atomic.LoadInt32(&u.down) == 1 {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClockOfflineProbing(t *testing.T) {
+	offline := make(chan error, 1)
+	online := make(chan struct{}, 1)
+	transport := &unreachable{down: 1}
+
+	clock := dogrustest.NewClock(time.Now())
+	intake := dogrustest.NewIntake(t, "key")
+	c := newClockClient(t, clock, intake, dogrus.Opts{
+		FlushPeriod:  time.Hour,
+		MaxBatchSize: 1,
+		HTTPClient:   &http.Client{Transport: transport},
+		Offline: &dogrus.Offline{
+			Threshold:     1,
+			ProbeInterval: 10 * time.Second,
+			DrainInterval: time.Second,
+			OnOffline:     func(err error) { offline <- err },
+			OnOnline:      func() { online <- struct{}{} },
+		},
+	})
+
+	c.Enqueue([]byte(`{"message":"hello"}`))
+	if err := <-offline; !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("went offline with %v", err)
+	}
+	if h := c.Health(); !h.Offline || h.Backlog != 1 {
+		t.Fatalf("Offline %v Backlog %d, want true and 1", h.Offline, h.Backlog)
+	}
+
+	// the flush timer and the prober, failing a probe
+	waitTimers(t, clock, 2)
+	clock.Advance(10 * time.Second)
+	waitTimers(t, clock, 2)
+
+	atomic.StoreInt32(&transport.down, 0)
+	clock.Advance(10*time.Second - time.Nanosecond)
+	if clock.Timers() != 2 || len(intake.Batches()) != 0 {
+		t.Fatal("probed before ProbeInterval")
+	}
+
+	clock.Advance(time.Nanosecond)
+	intake.WaitForEntries(t, 1, 5*time.Second)
+
+	// the backlog is empty after the pause following the last batch
+	waitTimers(t, clock, 2)
+	clock.Advance(time.Second)
+
+	select {
+	case <-online:
+	case <-time.After(5 * time.Second):
+		t.Fatal("still offline after sending the backlog")
+	}
+	if h := c.Health(); h.Offline || h.Backlog != 0 {
+		t.Fatalf("Offline %v Backlog %d, want false and 0", h.Offline, h.Backlog)
+	}
+}
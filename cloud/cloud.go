@@ -0,0 +1,251 @@
+// Package cloud queries the instance metadata service of EC2, GCE and Azure
+// virtual machines, returning the instance details as tags, like the ones
+// the Datadog Agent would attach to logs:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//	hook := dogrus.New(apiKey, dogrus.Opts{Tags: cloud.Tags(ctx)})
+//
+// The metadata services are only queried when Tags is called, so it's
+// meant to be called once at startup.
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"path"
+	"time"
+)
+
+// Tags describing the instance.
+const (
+	ProviderTag         = "cloud_provider"
+	RegionTag           = "region"
+	AvailabilityZoneTag = "availability-zone"
+	InstanceTypeTag     = "instance-type"
+	InstanceIDTag       = "instance-id"
+)
+
+// Instance describes the virtual machine the process is running on.
+type Instance struct {
+	// Provider is aws, gcp or azure.
+	Provider string
+
+	Region           string
+	AvailabilityZone string
+	InstanceType     string
+	InstanceID       string
+}
+
+// Tags returns the tags of the instance, in key:value format, leaving out
+// the empty ones.
+func (i Instance) Tags() []string {
+	var tags []string
+
+	add := func(key, value string) {
+		if value != "" {
+			tags = append(tags, key+":"+value)
+		}
+	}
+
+	add(ProviderTag, i.Provider)
+	add(RegionTag, i.Region)
+	add(AvailabilityZoneTag, i.AvailabilityZone)
+	add(InstanceTypeTag, i.InstanceType)
+	add(InstanceIDTag, i.InstanceID)
+
+	return tags
+}
+
+// defaultTimeout bounds detection when ctx has no deadline, since outside
+// of a cloud the metadata address doesn't answer at all.
+const defaultTimeout = 2 * time.Second
+
+// client doesn't use the proxy from the environment, the metadata services
+// are only reachable from the instance itself.
+var client = &http.Client{
+	Transport: &http.Transport{Proxy: nil},
+}
+
+// Detect queries the metadata services of every provider at the same time,
+// returning the instance found by the first one answering. It returns false
+// if none answers before ctx is done.
+func Detect(ctx context.Context) (Instance, bool) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	detectors := []func(context.Context) (Instance, error){ec2, gce, azure}
+	found := make(chan Instance, len(detectors))
+
+	for _, detect := range detectors {
+		go func(detect func(context.Context) (Instance, error)) {
+			instance, err := detect(ctx)
+			if err != nil {
+				instance = Instance{}
+			}
+			found <- instance
+		}(detect)
+	}
+
+	for range detectors {
+		instance := <-found
+		if instance.Provider != "" {
+			return instance, true
+		}
+	}
+
+	return Instance{}, false
+}
+
+// Tags returns the tags of the instance found by Detect, nil if there is
+// none.
+func Tags(ctx context.Context) []string {
+	instance, ok := Detect(ctx)
+	if !ok {
+		return nil
+	}
+
+	return instance.Tags()
+}
+
+// metadataAddr is the link-local address of every metadata service.
+const metadataAddr = "http://169.254.169.254"
+
+// ec2 reads the instance identity document, using IMDSv2.
+func ec2(ctx context.Context) (Instance, error) {
+	token, err := get(ctx, "PUT", metadataAddr+"/latest/api/token", map[string]string{
+		"X-aws-ec2-metadata-token-ttl-seconds": "60",
+	})
+	if err != nil {
+		return Instance{}, err
+	}
+
+	body, err := get(ctx, "GET", metadataAddr+"/latest/dynamic/instance-identity/document", map[string]string{
+		"X-aws-ec2-metadata-token": string(token),
+	})
+	if err != nil {
+		return Instance{}, err
+	}
+
+	var doc struct {
+		Region           string `json:"region"`
+		AvailabilityZone string `json:"availabilityZone"`
+		InstanceType     string `json:"instanceType"`
+		InstanceID       string `json:"instanceId"`
+	}
+	err = json.Unmarshal(body, &doc)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	return Instance{
+		Provider:         "aws",
+		Region:           doc.Region,
+		AvailabilityZone: doc.AvailabilityZone,
+		InstanceType:     doc.InstanceType,
+		InstanceID:       doc.InstanceID,
+	}, nil
+}
+
+// gce reads the instance metadata of Google Compute Engine.
+func gce(ctx context.Context) (Instance, error) {
+	body, err := get(ctx, "GET", "http://metadata.google.internal/computeMetadata/v1/instance/?recursive=true", map[string]string{
+		"Metadata-Flavor": "Google",
+	})
+	if err != nil {
+		return Instance{}, err
+	}
+
+	var doc struct {
+		ID          json.Number `json:"id"`
+		Zone        string      `json:"zone"`
+		MachineType string      `json:"machineType"`
+	}
+	err = json.Unmarshal(body, &doc)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	// zone and machine type are paths like projects/1234/zones/us-east1-b
+	zone := path.Base(doc.Zone)
+	region := zone
+	if len(zone) > 2 && zone[len(zone)-2] == '-' {
+		region = zone[:len(zone)-2]
+	}
+
+	return Instance{
+		Provider:         "gcp",
+		Region:           region,
+		AvailabilityZone: zone,
+		InstanceType:     path.Base(doc.MachineType),
+		InstanceID:       doc.ID.String(),
+	}, nil
+}
+
+// azure reads the compute metadata of Azure virtual machines.
+func azure(ctx context.Context) (Instance, error) {
+	body, err := get(ctx, "GET", metadataAddr+"/metadata/instance/compute?api-version=2021-02-01", map[string]string{
+		"Metadata": "true",
+	})
+	if err != nil {
+		return Instance{}, err
+	}
+
+	var doc struct {
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		VMSize   string `json:"vmSize"`
+		VMID     string `json:"vmId"`
+	}
+	err = json.Unmarshal(body, &doc)
+	if err != nil {
+		return Instance{}, err
+	}
+
+	return Instance{
+		Provider:         "azure",
+		Region:           doc.Location,
+		AvailabilityZone: doc.Zone,
+		InstanceType:     doc.VMSize,
+		InstanceID:       doc.VMID,
+	}, nil
+}
+
+// errStatus is returned for responses other than 200 OK.
+type errStatus int
+
+func (e errStatus) Error() string {
+	return "cloud: unexpected response status " + http.StatusText(int(e))
+}
+
+// get makes a request to a metadata service and returns the response body.
+func get(ctx context.Context, method, url string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errStatus(res.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, 1<<20))
+}
@@ -0,0 +1,139 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	handlerMu sync.Mutex
+	handler   http.HandlerFunc
+)
+
+func init() {
+	// the detectors still running when Detect returns use the client
+	// after the test, so the transport is replaced once
+	client.Transport = roundTripper(func(req *http.Request) (*http.Response, error) {
+		handlerMu.Lock()
+		h := handler
+		handlerMu.Unlock()
+
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		if rec.Code == http.StatusNotFound {
+			return nil, errors.New("unreachable")
+		}
+		return rec.Result(), nil
+	})
+}
+
+// serveMetadata routes the requests to the metadata services to h, failing
+// the ones it doesn't answer.
+func serveMetadata(h http.HandlerFunc) {
+	handlerMu.Lock()
+	defer handlerMu.Unlock()
+
+	handler = h
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    Instance
+	}{
+		{
+			"ec2",
+			func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == "PUT" && r.URL.Path == "/latest/api/token":
+					w.Write([]byte("token"))
+				case r.URL.Path == "/latest/dynamic/instance-identity/document" && r.Header.Get("X-aws-ec2-metadata-token") == "token":
+					w.Write([]byte(`{"region":"us-east-1","availabilityZone":"us-east-1a","instanceType":"m5.large","instanceId":"i-0abc"}`))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			},
+			Instance{"aws", "us-east-1", "us-east-1a", "m5.large", "i-0abc"},
+		},
+		{
+			"gce",
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.Host != "metadata.google.internal" || r.Header.Get("Metadata-Flavor") != "Google" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(`{"id":1234567890123,"zone":"projects/1234/zones/europe-west1-b","machineType":"projects/1234/machineTypes/n2-standard-4"}`))
+			},
+			Instance{"gcp", "europe-west1", "europe-west1-b", "n2-standard-4", "1234567890123"},
+		},
+		{
+			"azure",
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/metadata/instance/compute" || r.Header.Get("Metadata") != "true" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(`{"location":"westeurope","zone":"2","vmSize":"Standard_D2s_v3","vmId":"02aab8a4"}`))
+			},
+			Instance{"azure", "westeurope", "2", "Standard_D2s_v3", "02aab8a4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveMetadata(tt.handler)
+
+			got, ok := Detect(context.Background())
+			if !ok || got != tt.want {
+				t.Errorf("detected %+v, %v, want %+v", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectNone(t *testing.T) {
+	serveMetadata(func(w http.ResponseWriter, r *http.Request) {
+		// a metadata service answering with an error isn't a provider
+		if r.URL.Path == "/latest/api/token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if instance, ok := Detect(ctx); ok {
+		t.Errorf("detected %+v, want none", instance)
+	}
+	if tags := Tags(ctx); tags != nil {
+		t.Errorf("tags are %q, want nil", tags)
+	}
+}
+
+func TestInstanceTags(t *testing.T) {
+	tags := Instance{Provider: "aws", Region: "us-east-1", InstanceID: "i-0abc"}.Tags()
+
+	want := []string{"cloud_provider:aws", "region:us-east-1", "instance-id:i-0abc"}
+	if len(tags) != len(want) {
+		t.Fatalf("tags are %q, want %q", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Fatalf("tags are %q, want %q", tags, want)
+		}
+	}
+}
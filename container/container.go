@@ -0,0 +1,118 @@
+// Package container detects the container and Kubernetes pod the process is
+// running in, returning them as the tags the Datadog Agent would attach, so
+// logs sent without the agent still correlate with infrastructure views:
+//
+//	hook := dogrus.New(apiKey, dogrus.Opts{Tags: container.Tags()})
+//
+// Pod name, namespace and node are read from the environment variables
+// usually filled by the downward API:
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	- name: POD_NAMESPACE
+//	  valueFrom: {fieldRef: {fieldPath: metadata.namespace}}
+//	- name: NODE_NAME
+//	  valueFrom: {fieldRef: {fieldPath: spec.nodeName}}
+package container
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Tags attached by the Datadog Agent to container logs.
+const (
+	IDTag        = "container_id"
+	PodNameTag   = "pod_name"
+	NamespaceTag = "kube_namespace"
+	NodeTag      = "kube_node"
+)
+
+// Files read during detection.
+const (
+	cgroupPath    = "/proc/self/cgroup"
+	mountinfoPath = "/proc/self/mountinfo"
+	namespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// containerID matches the ids used by Docker, containerd and CRI-O.
+var containerID = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// Tags returns the tags describing the container and the pod, in key:value
+// format, leaving out the ones that can't be detected. It returns nil when
+// not running in a container.
+func Tags() []string {
+	var tags []string
+
+	if id := ID(); id != "" {
+		tags = append(tags, IDTag+":"+id)
+	}
+
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return tags
+	}
+
+	// the hostname of a pod is its name, unless overridden by the spec
+	pod := os.Getenv("POD_NAME")
+	if pod == "" {
+		pod, _ = os.Hostname()
+	}
+	if pod != "" {
+		tags = append(tags, PodNameTag+":"+pod)
+	}
+
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		b, _ := os.ReadFile(namespacePath)
+		namespace = strings.TrimSpace(string(b))
+	}
+	if namespace != "" {
+		tags = append(tags, NamespaceTag+":"+namespace)
+	}
+
+	if node := os.Getenv("NODE_NAME"); node != "" {
+		tags = append(tags, NodeTag+":"+node)
+	}
+
+	return tags
+}
+
+// ID returns the id of the container the process is running in, found in
+// its cgroup or, with cgroup v2, in its mounts. It returns an empty string
+// if there is none.
+func ID() string {
+	if id := find(cgroupPath, ""); id != "" {
+		return id
+	}
+
+	// with cgroup v2 the cgroup is just "0::/", but the files mounted by
+	// the runtime, like /etc/hostname, come from the container directory
+	return find(mountinfoPath, "/containers/")
+}
+
+// find returns the first container id in the lines of the file at path
+// that contain marker.
+func find(path, marker string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.Contains(line, marker) {
+			continue
+		}
+
+		if id := containerID.FindString(line); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,104 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const id = "3f4e2b1c9a8d7e6f5a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f7a6b5c4d3e2f"
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		marker  string
+		want    string
+	}{
+		{
+			"cgroup v1",
+			"12:pids:/docker/" + id + "\n11:memory:/docker/" + id + "\n",
+			"",
+			id,
+		},
+		{
+			"cgroup v1 kubepods",
+			"1:name=systemd:/kubepods/burstable/pod1234/cri-containerd-" + id + ".scope\n",
+			"",
+			id,
+		},
+		{
+			"cgroup v2",
+			"0::/\n",
+			"",
+			"",
+		},
+		{
+			"mountinfo",
+			"100 90 0:50 / / rw - overlay overlay rw\n" +
+				"110 100 254:1 /var/lib/docker/containers/" + id + "/hostname /etc/hostname rw - ext4 /dev/vda1 rw\n",
+			"/containers/",
+			id,
+		},
+		{
+			"mountinfo outside a container",
+			"120 100 254:1 /volumes/" + id + " /data rw - ext4 /dev/vda1 rw\n",
+			"/containers/",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := find(path, tt.marker); got != tt.want {
+				t.Errorf("found %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMissingFile(t *testing.T) {
+	if got := find(filepath.Join(t.TempDir(), "missing"), ""); got != "" {
+		t.Errorf("found %q in a missing file", got)
+	}
+}
+
+func TestTagsKubernetes(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("POD_NAME", "api-7d9f8")
+	t.Setenv("POD_NAMESPACE", "prod")
+	t.Setenv("NODE_NAME", "node-3")
+
+	tags := Tags()
+
+	// the container id depends on where the test runs
+	if len(tags) > 0 && tags[0] == IDTag+":"+ID() {
+		tags = tags[1:]
+	}
+
+	want := []string{"pod_name:api-7d9f8", "kube_namespace:prod", "kube_node:node-3"}
+	if len(tags) != len(want) {
+		t.Fatalf("tags are %q, want %q", tags, want)
+	}
+	for i := range want {
+		if tags[i] != want[i] {
+			t.Fatalf("tags are %q, want %q", tags, want)
+		}
+	}
+}
+
+func TestTagsOutsideKubernetes(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("POD_NAME", "api-7d9f8")
+
+	for _, tag := range Tags() {
+		if tag != IDTag+":"+ID() {
+			t.Errorf("unexpected tag %q outside Kubernetes", tag)
+		}
+	}
+}
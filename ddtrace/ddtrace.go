@@ -0,0 +1,42 @@
+// Package ddtrace correlates logs with dd-trace-go traces.
+//
+// When an entry is logged with a context carrying a dd-trace-go span (see
+// logrus.WithContext), its ids are attached to the entry so the log shows up
+// alongside the trace:
+//
+//	hook := dogrus.New(apiKey, dogrus.Opts{
+//		Transformers: []dogrus.Transformer{ddtrace.Transformer()},
+//	})
+//
+// It lives in its own package so that dogrus itself doesn't depend on the
+// tracer.
+package ddtrace
+
+import (
+	"strconv"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+// Transformer returns a dogrus.Transformer adding dogrus.TraceIDKey and
+// dogrus.SpanIDKey to entries logged with a span in their context.
+func Transformer() dogrus.Transformer {
+	return func(e *logrus.Entry) *logrus.Entry {
+		if e.Context == nil {
+			return e
+		}
+
+		span, ok := tracer.SpanFromContext(e.Context)
+		if !ok {
+			return e
+		}
+
+		sc := span.Context()
+		e.Data[dogrus.TraceIDKey] = strconv.FormatUint(sc.TraceID(), 10)
+		e.Data[dogrus.SpanIDKey] = strconv.FormatUint(sc.SpanID(), 10)
+
+		return e
+	}
+}
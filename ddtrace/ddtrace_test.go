@@ -0,0 +1,62 @@
+package ddtrace
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/mocktracer"
+	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
+)
+
+func TestTransformer(t *testing.T) {
+	mt := mocktracer.Start()
+	defer mt.Stop()
+
+	sink := dogrus.NewMemorySink()
+	d := dogrus.New("key", dogrus.Opts{
+		Sink:         sink,
+		Transformers: []dogrus.Transformer{Transformer()},
+	})
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+	logger.AddHook(d)
+
+	span, ctx := tracer.StartSpanFromContext(context.Background(), "checkout")
+	logger.WithContext(ctx).Info("traced")
+	span.Finish()
+
+	logger.WithContext(context.Background()).Info("no span")
+	logger.Info("no context")
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := sink.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	sc := span.Context()
+	if sc.TraceID() == 0 || sc.SpanID() == 0 {
+		t.Fatal("the mock tracer started a span without ids")
+	}
+	traceID := strconv.FormatUint(sc.TraceID(), 10)
+	spanID := strconv.FormatUint(sc.SpanID(), 10)
+	if e := entries[0]; e[dogrus.TraceIDKey] != traceID || e[dogrus.SpanIDKey] != spanID {
+		t.Errorf("traced entry is %v, want trace %s and span %s", e, traceID, spanID)
+	}
+	for _, e := range entries[1:] {
+		if _, ok := e[dogrus.TraceIDKey]; ok {
+			t.Errorf("entry %v has a trace id without a span", e)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package dogrus
+
+import "time"
+
+// DeadLetter is a batch that couldn't be delivered to Datadog, after
+// exhausting all the retries.
+type DeadLetter struct {
+	// Entries are the formatted entries of the batch, as they would have
+	// been sent.
+	Entries [][]byte
+
+	// Err is the error of the last attempt.
+	Err error
+
+	// Time is when the batch was given up.
+	Time time.Time
+}
+
+// deadLetter hands an undeliverable batch to OnDeadLetter and keeps it for
+// DeadLetters, if configured.
+func (c *Client) deadLetter(err error, batch []record) {
+	if c.opts.OnDeadLetter == nil && c.opts.MaxDeadLetters <= 0 {
+		return
+	}
+
+	dl := DeadLetter{
+		Entries: entries(batch),
+		Err:     err,
+		Time:    c.opts.Clock.Now(),
+	}
+
+	if c.opts.OnDeadLetter != nil {
+		c.opts.OnDeadLetter(dl)
+	}
+
+	if c.opts.MaxDeadLetters <= 0 {
+		return
+	}
+
+	c.deadLettersMu.Lock()
+	defer c.deadLettersMu.Unlock()
+
+	// keep the most recent ones
+	if len(c.deadLetters) == c.opts.MaxDeadLetters {
+		copy(c.deadLetters, c.deadLetters[1:])
+		c.deadLetters = c.deadLetters[:len(c.deadLetters)-1]
+	}
+
+	c.deadLetters = append(c.deadLetters, dl)
+}
+
+// DeadLetters returns the undeliverable batches retained so far, oldest
+// first, and forgets them. At most MaxDeadLetters batches are retained.
+func (c *Client) DeadLetters() []DeadLetter {
+	c.deadLettersMu.Lock()
+	defer c.deadLettersMu.Unlock()
+
+	dls := c.deadLetters
+	c.deadLetters = nil
+
+	return dls
+}
+
+// DeadLetters returns the undeliverable batches retained so far by the hook
+// Client, see Client.DeadLetters.
+func (d *Hook) DeadLetters() []DeadLetter {
+	return d.client.DeadLetters()
+}
@@ -0,0 +1,20 @@
+package dogrus
+
+import "log"
+
+// newDebugLogger returns the logger writing to Opts.DebugLogger, nil if it's
+// not set.
+func newDebugLogger(opts Opts) *log.Logger {
+	if opts.DebugLogger == nil {
+		return nil
+	}
+
+	return log.New(opts.DebugLogger, "dogrus: ", log.LstdFlags|log.Lmicroseconds)
+}
+
+// debugf reports what the client is doing to Opts.DebugLogger, if set.
+func (c *Client) debugf(format string, args ...interface{}) {
+	if c.debug != nil {
+		c.debug.Printf(format, args...)
+	}
+}
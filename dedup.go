@@ -0,0 +1,145 @@
+package dogrus
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RepeatCountKey is the field added by Opts.DedupWindow to the entry
+// summarizing the duplicates of an entry, telling how many were collapsed.
+const RepeatCountKey = "repeat_count"
+
+// maxDuplicates bounds the distinct entries tracked in a dedup window, the
+// ones beyond it are not deduplicated.
+const maxDuplicates = 10000
+
+// deduper collapses identical entries logged within a window.
+type deduper struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	seen   map[uint64]*duplicates
+	closed bool
+}
+
+// duplicates tracks an entry logged in the current window.
+type duplicates struct {
+	last  *logrus.Entry
+	count int
+	timer Timer
+}
+
+func newDeduper(window time.Duration) *deduper {
+	return &deduper{
+		window: window,
+		seen:   make(map[uint64]*duplicates),
+	}
+}
+
+// dedup tells if entry is a duplicate to be collapsed. The first entry of a
+// window is sent as usual, the duplicates that follow it are counted and
+// sent as a single entry, carrying RepeatCountKey, when the window ends.
+func (d *Hook) dedup(entry *logrus.Entry) bool {
+	if d.deduper == nil {
+		return false
+	}
+
+	key := dedupKey(entry)
+
+	dd := d.deduper
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+
+	if dd.closed {
+		return false
+	}
+
+	if dup, ok := dd.seen[key]; ok {
+		dup.last = entry
+		dup.count++
+		return true
+	}
+
+	if len(dd.seen) < maxDuplicates {
+		dup := &duplicates{}
+		dup.timer = d.opts.Clock.AfterFunc(dd.window, func() {
+			d.repeated(key, dup)
+		})
+		dd.seen[key] = dup
+	}
+
+	return false
+}
+
+// repeated ends the window of dup, sending the entry summarizing its
+// duplicates if there are any. Errors are reported to OnError, since there
+// is no logrus call to return them to.
+func (d *Hook) repeated(key uint64, dup *duplicates) {
+	var err error
+	defer d.recoverFire(&err)
+
+	dd := d.deduper
+	dd.mu.Lock()
+	if dd.seen[key] == dup {
+		delete(dd.seen, key)
+	}
+	last, count := dup.last, dup.count
+	dd.mu.Unlock()
+
+	if count == 0 {
+		return
+	}
+
+	e := copyEntry(last)
+	e.Data[RepeatCountKey] = count
+
+	err = d.enqueue(e, 1)
+	if err != nil && d.opts.OnError != nil {
+		d.opts.OnError(err, nil)
+	}
+}
+
+// closeDedup ends every window, sending the pending summaries before the
+// clients are closed.
+func (d *Hook) closeDedup() {
+	if d.deduper == nil {
+		return
+	}
+
+	dd := d.deduper
+	dd.mu.Lock()
+	dd.closed = true
+	pending := dd.seen
+	dd.seen = nil
+	dd.mu.Unlock()
+
+	for key, dup := range pending {
+		if dup.timer.Stop() {
+			d.repeated(key, dup)
+		}
+	}
+}
+
+// dedupKey returns a hash of the level, the message and the fields of
+// entry, identifying its duplicates.
+func dedupKey(entry *logrus.Entry) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d\x00%s", entry.Level, entry.Message)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(h, "\x00%s=%v", k, entry.Data[k])
+	}
+
+	return h.Sum64()
+}
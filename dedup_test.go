@@ -0,0 +1,90 @@
+package dogrus_test
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDedupWindow(t *testing.T) {
+	sink := dogrus.NewMemorySink()
+	clock := dogrustest.NewClock(time.Now())
+	d := dogrus.New("key", dogrus.Opts{
+		Sink:        sink,
+		Clock:       clock,
+		FlushPeriod: time.Hour,
+		DedupWindow: time.Minute,
+	})
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+	logger.AddHook(d)
+
+	for i := 0; i < 3; i++ {
+		logger.Error("disk full")
+	}
+	logger.Error("disk almost full")
+	logger.WithField("disk", "sdb").Error("disk full")
+	logger.Warn("disk full")
+
+	// the first entry of each window is sent right away
+	if err := d.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	assertMessages(t, sink, "disk full", "disk almost full", "disk full", "disk full")
+
+	// the duplicates are summarized when the window ends
+	clock.Advance(time.Minute)
+	waitFor(t, "the summary", func() bool {
+		d.Flush()
+		return sink.Len() == 5
+	})
+	last := decodedEntries(t, sink)[4]
+	if last["message"] != "disk full" || last["status"] != "error" || last[dogrus.RepeatCountKey] != float64(2) || last["disk"] != nil {
+		t.Fatalf("summary is %v, want disk full repeated twice", last)
+	}
+
+	// a new window starts, its pending duplicates are sent by Close
+	logger.Error("disk full")
+	logger.Error("disk full")
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := decodedEntries(t, sink)
+	if len(entries) != 7 {
+		t.Fatalf("got %d entries, want 7", len(entries))
+	}
+	if entries[5][dogrus.RepeatCountKey] != nil || entries[6][dogrus.RepeatCountKey] != float64(1) {
+		t.Errorf("entries are %v and %v, want the first and a summary of one duplicate", entries[5], entries[6])
+	}
+}
+
+func decodedEntries(t *testing.T, sink *dogrus.MemorySink) []map[string]interface{} {
+	t.Helper()
+
+	entries, err := sink.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return entries
+}
+
+func assertMessages(t *testing.T, sink *dogrus.MemorySink, messages ...string) {
+	t.Helper()
+
+	entries := decodedEntries(t, sink)
+	if len(entries) != len(messages) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(messages))
+	}
+	for i, e := range entries {
+		if e["message"] != messages[i] {
+			t.Errorf("entry %d is %q, want %q", i, e["message"], messages[i])
+		}
+	}
+}
@@ -4,8 +4,14 @@
 package dogrus
 
 import (
-	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -14,13 +20,30 @@ import (
 // Hook is a logrus hook that sends logs to Datadog using HTTP and
 // logrus JSONFormatter for marshalling entries.
 // Logs are sent in batches to avoid creation of too many connections.
+// Fire only formats and enqueues entries, all network I/O happens in the
+// background sender goroutine of the hook Client, except for the events of
+// fatal and panic entries in Opts.EventLevels.
 // Use New() to create a initialize a new hook.
 type Hook struct {
-	key       string
-	opts      Opts
-	lastFlush time.Time
-	timer     *time.Timer
-	batch     chan []byte
+	// counters are accessed atomically, keep them first for 64-bit alignment
+	sampledOut uint64
+	minLevel   uint32 // logrus.Level, changed by SetMinLevel
+
+	client   *Client
+	opts     Opts
+	adaptive *adaptiveSampler
+	denied   fieldSet
+	allowed  fieldSet
+	routes   routes
+	mirrors  []*Client
+	runtime  *runtimeStats
+	deduper  *deduper
+
+	priorities map[logrus.Level]bool
+	events     map[logrus.Level]bool
+	poster     *eventPoster
+
+	sampleRates atomic.Value // SampleRates, changed by SetSampleRates
 }
 
 // Opts are variables for tuning perfomances.
@@ -29,24 +52,464 @@ type Opts struct {
 	// FlushPeriod sets the interval of time to wait before triggering a flush.
 	FlushPeriod time.Duration
 
+	// FlushJitter randomizes each FlushPeriod by up to this fraction of it,
+	// in both directions, so replicas started together don't flush at the
+	// same time. For example 0.2 waits between 24 and 36 seconds with the
+	// default period.
+	// By default there is no jitter.
+	FlushJitter float64
+
 	// MaxBatchSize sets the size of the batch that will force a flush.
 	// A MaxBatchSize of 1 will make each entry sent instantly.
 	MaxBatchSize int
 
+	// MaxBatchBytes sets the size in bytes of the batch that will force a
+	// flush. Batches larger than this are split in multiple requests.
+	// By default is 5MB, the maximum payload accepted by Datadog.
+	MaxBatchBytes int
+
+	// MaxEntriesPerRequest sets how many entries can be sent in a single
+	// request. Batches with more entries are split in multiple requests.
+	// By default is 1000, the maximum accepted by Datadog.
+	MaxEntriesPerRequest int
+
+	// MaxEntryBytes sets the maximum size of a single formatted entry.
+	// Larger entries are handled according to OversizePolicy.
+	// By default is 1MB, the maximum accepted by Datadog.
+	MaxEntryBytes int
+
+	// OversizePolicy decides what happens to entries larger than
+	// MaxEntryBytes. By default is OversizeTruncate.
+	OversizePolicy OversizePolicy
+
+	// QueueSize sets how many entries can be waiting to be sent.
+	// By default is 1000.
+	QueueSize int
+
+	// MaxQueuedBytes caps the serialized size of the entries held in
+	// memory: queued, batched, being sent or retried. Once reached, new
+	// entries are handled by OverflowPolicy like when the queue is full.
+	// The backlog of Offline has its own limit.
+	// By default there is no limit.
+	MaxQueuedBytes int
+
+	// OverflowPolicy decides what happens to new entries when the queue is
+	// full. By default is OverflowBlock.
+	OverflowPolicy OverflowPolicy
+
+	// Watermarks, if set, are notified when the queue usage crosses its
+	// thresholds.
+	Watermarks *Watermarks
+
+	// Sink, if set, replaces Datadog as the destination of the batches,
+	// which still go through the same queue, retries, rate limits and
+	// circuit breaker. PostURL, Protocol and Compress are ignored.
+	Sink Sink
+
 	// PostURL is the address where HTTP request will be sent.
-	// By default is Datadog EU server (https://http-intake.logs.datadoghq.eu/v1/input).
+	// By default is the intake of Site. When it points to a local relay, such
+	// as an agent, the API key can be left empty and the DD-API-KEY header
+	// is not sent.
 	PostURL string
 
+	// Protocol selects how entries are delivered to Datadog.
+	// By default is ProtocolHTTP.
+	// It's ignored if Sink is set.
+	Protocol Protocol
+
+	// TCPAddr is the host:port address entries are sent to with
+	// ProtocolTCP or ProtocolAgent.
+	// By default is the TCP intake of Site, or localhost:10518 for the
+	// agent.
+	TCPAddr string
+
+	// Site is the Datadog site logs are sent to, such as datadoghq.com or
+	// us5.datadoghq.com. It's ignored if PostURL, or TCPAddr with
+	// ProtocolTCP, is set.
+	// By default is Datadog EU site (datadoghq.eu).
+	Site string
+
+	// Service is the name of the service emitting logs, attached to every
+	// entry as the service attribute.
+	Service string
+
+	// Env is the environment the service is running in, attached to every
+	// entry as the env tag.
+	Env string
+
+	// Version is the version of the service, attached to every entry as
+	// the version tag.
+	Version string
+
+	// Hostname is the name of the host, attached to every entry as the
+	// hostname attribute.
+	// By default is the DD_HOSTNAME environment variable, or the name
+	// reported by the kernel if it's not set, see os.Hostname.
+	Hostname string
+
+	// ProcessAttributes attaches the pid, the executable name, the Go
+	// version and the app version of the process to every entry, see
+	// ProcessIDKey.
+	ProcessAttributes bool
+
+	// RuntimeStats attaches the goroutine count and the heap size, read at
+	// most once per second, to every logrus entry, see GoroutinesKey.
+	RuntimeStats bool
+
+	// Tags are attached to every entry, in key:value format.
+	Tags []string
+
+	// Levels are the levels of the entries sent to Datadog, see LevelsFrom
+	// to select them by severity.
+	// By default are all levels.
+	Levels []logrus.Level
+
+	// PriorityLevels are the levels of the entries that are sent right
+	// away, along with the pending batch, instead of waiting for it to fill
+	// up or for FlushPeriod, such as LevelsFrom(logrus.ErrorLevel).
+	// By default every entry is batched.
+	PriorityLevels []logrus.Level
+
+	// EventLevels are the levels of the entries that, besides being logged,
+	// are posted to the Datadog Events API, such as fatal and panic, so
+	// crashes show up in the event stream. Events are posted in background,
+	// except for fatal and panic ones which are posted before Fire returns,
+	// since the process ends right after those entries.
+	// It's ignored if Sink is set.
+	// By default no event is posted.
+	EventLevels []logrus.Level
+
+	// EventsURL is the address of the Events API.
+	// By default is the one of Site.
+	EventsURL string
+
+	// SampleRates sets the fraction of entries sent to Datadog for each
+	// level, between 0 (none) and 1 (all of them). The entries of levels not
+	// in the map are all sent.
+	// The map is copied by New, the rates can be changed later with
+	// SetSampleRates.
+	SampleRates map[logrus.Level]float64
+
+	// AdaptiveSampling, if set, samples low-severity entries when log
+	// volume spikes or the queue fills up. Kept entries are marked with
+	// SampleRateKey.
+	AdaptiveSampling *AdaptiveSampling
+
+	// DedupWindow, if set, collapses identical entries, with the same
+	// level, message and fields, logged within the window: the first one is
+	// sent right away, the duplicates as a single entry carrying
+	// RepeatCountKey when the window ends.
+	// By default entries are not deduplicated.
+	DedupWindow time.Duration
+
+	// Transformers are run in order on every entry before formatting, to
+	// rename fields, derive new ones or skip entries altogether.
+	Transformers []Transformer
+
+	// DisableErrorAttributes stops the hook from adding the error.message,
+	// error.kind and error.stack attributes to entries with an error field
+	// (see logrus.WithError), used by Datadog Error Tracking.
+	DisableErrorAttributes bool
+
+	// StackExtractor extracts the error.stack attribute from errors.
+	// By default is PkgErrorsStack.
+	StackExtractor StackExtractor
+
+	// CaptureStack adds the stack of the logging goroutine as error.stack to
+	// entries at error level or above that don't carry one in their error.
+	CaptureStack bool
+
+	// DisableCallerAttributes stops the hook from replacing the func and file
+	// keys added by logrus when reporting the caller (see
+	// logrus.SetReportCaller) with the logger.name, logger.method_name,
+	// logger.file and logger.line attributes rendered by Datadog.
+	DisableCallerAttributes bool
+
+	// DenyFields are the names of fields removed from every entry, such as
+	// password or authorization. Names are case-insensitive.
+	DenyFields []string
+
+	// AllowFields, if set, are the only fields forwarded to Datadog, any
+	// other field is removed. Names are case-insensitive. Attributes added
+	// by the hook itself, like service, ddtags, error.message or
+	// logger.name, are always kept, and override fields like dd.service
+	// are still applied. The error field is used for the error attributes
+	// even when it isn't allowed.
+	AllowFields []string
+
+	// ScrubRules are applied to the message and to the string fields of
+	// every entry before formatting, including the strings nested in maps
+	// and slices, to keep sensitive data out of Datadog.
+	// See DefaultScrubRules for a set of built-in rules.
+	ScrubRules []ScrubRule
+
 	// Formatter is the formatter used by this hook to marshal each logrus
-	// entry into a JSON.
+	// entry into a JSON. See DatadogFormatter for more control on the
+	// layout.
 	// It defaults to logrus.JSONFormatter configured with standard Datadog
 	// keys.
 	Formatter logrus.Formatter
+
+	// Marshal, if set, replaces encoding/json in the default Formatter, to
+	// plug in a faster encoder. The output keeps the same layout.
+	// It's ignored if Formatter is set.
+	Marshal MarshalFunc
+
+	// Statuses maps the level of each entry to the Datadog status added as
+	// StatusKey, so severity facets and monitors work without remapping
+	// rules. Levels missing from the map get no status.
+	// By default is DatadogStatuses.
+	Statuses map[logrus.Level]string
+
+	// DisableStatus stops the hook from adding StatusKey, leaving the
+	// severity to the logrus level name.
+	DisableStatus bool
+
+	// APIKeyFunc, if set, is called before every request to get the API
+	// key, instead of using the one given to New, so it can be rotated by a
+	// secrets manager. A failure counts as a failed request.
+	APIKeyFunc func() (string, error)
+
+	// APIKeyInPath sends the API key as the last segment of the PostURL
+	// path, like /v1/input/<key>, instead of the DD-API-KEY header, for
+	// relays mimicking the legacy intake.
+	APIKeyInPath bool
+
+	// Headers are added to every HTTP request, such as DD-EVP-ORIGIN or
+	// the credentials of an internal gateway. They can't replace the
+	// DD-API-KEY, Content-Type and Content-Encoding headers set by the
+	// client.
+	Headers map[string]string
+
+	// BeforeSend are called in order with every HTTP request right before
+	// it's made, each one with the body returned by the previous one, to
+	// sign, encrypt or rewrite the payload for an internal relay.
+	// They are not called for ProtocolTCP, ProtocolAgent and Sink.
+	BeforeSend []BeforeSend
+
+	// RequestTimeout bounds the time spent sending a single batch to
+	// Datadog, including connection setup and reading the response.
+	// By default is 10 seconds.
+	RequestTimeout time.Duration
+
+	// Context is the base context for every request made by the hook.
+	// Cancelling it, or calling Close, aborts any in-flight request.
+	// By default is context.Background().
+	Context context.Context
+
+	// ProxyURL is the proxy used to reach PostURL.
+	// By default the proxy is taken from HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	// environment variables.
+	ProxyURL *url.URL
+
+	// TLSConfig is the TLS configuration of connections to Datadog, to
+	// trust a private CA or present a client certificate. See
+	// LoadTLSConfig.
+	// By default the system roots are used.
+	TLSConfig *tls.Config
+
+	// UnixSocketPath, if set, is the Unix domain socket connections are
+	// made to, instead of TCP, for agents or relays running as sidecars.
+	// HTTP requests still use the path of PostURL. It doesn't apply to
+	// ProtocolTCP.
+	UnixSocketPath string
+
+	// MaxIdleConnsPerHost sets how many idle connections to the intake are
+	// kept open for the next requests.
+	// By default is Concurrency, and at least 2.
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout sets how long an idle connection is kept open.
+	// By default is 90 seconds.
+	IdleConnTimeout time.Duration
+
+	// WarmUp opens a connection to the intake when the client is created,
+	// so the first batch doesn't wait for DNS resolution and TLS handshake.
+	WarmUp bool
+
+	// DisableHTTP2 stops the transport from negotiating HTTP/2, for proxies
+	// that don't handle it well.
+	DisableHTTP2 bool
+
+	// HTTPClient is the client used to make requests, created once and
+	// shared by every request. When set, ProxyURL, TLSConfig,
+	// MaxIdleConnsPerHost, IdleConnTimeout, DisableHTTP2 and UnixSocketPath
+	// are ignored and the client transport is used as is.
+	HTTPClient *http.Client
+
+	// OnError is called from the sender goroutines when a batch can't be
+	// delivered, along with the entries of that batch.
+	// Since logrus ignores errors returned by hooks and flushes happen in
+	// background, this is the only way to be notified of lost logs.
+	// batch is nil for errors not causing the loss of any entry, or when the
+	// entry couldn't be formatted. Use Skip to log from here.
+	OnError func(err error, batch [][]byte)
+
+	// OnFlush is called from the sender goroutines every time a batch is
+	// successfully delivered.
+	OnFlush func(stats FlushStats)
+
+	// DebugLogger, if set, receives a line for every step of the delivery:
+	// flushes and what triggered them, batch sizes, retries, response
+	// status codes and dropped entries. It's meant for diagnosing why logs
+	// don't show up in Datadog, and must not be a logger the hook is
+	// attached to.
+	DebugLogger io.Writer
+
+	// Clock is the source of time of the hook and its clients.
+	// By default is SystemClock.
+	Clock Clock
+
+	// Concurrency sets how many requests can be in flight at the same time,
+	// each sending a batch. The batches of a client are delivered in order
+	// only with a Concurrency of 1, otherwise OnError, OnFlush, OnDeadLetter
+	// and Sink can also be called concurrently.
+	// By default is 1.
+	Concurrency int
+
+	// RateLimit caps the requests and bytes sent per second. Batches over
+	// the limit wait their turn with OverflowBlock, otherwise they are
+	// discarded like undeliverable ones.
+	// By default there is no limit.
+	RateLimit RateLimit
+
+	// Compress enables gzip compression of request bodies.
+	Compress bool
+
+	// BodyFormat is how entries are framed in the body of HTTP requests.
+	// By default is JSONArray.
+	BodyFormat BodyFormat
+
+	// MaxRetries sets how many times a request is tried again after a
+	// network error or a 429/5xx response. By default failed requests are not
+	// retried.
+	MaxRetries int
+
+	// RetryBackoff is the time to wait before the first retry, doubled at
+	// every following one.
+	// By default is 1 second.
+	RetryBackoff time.Duration
+
+	// RetryFor, if set, replaces MaxRetries with a time budget: a failed
+	// batch is tried again until RetryFor has elapsed since its first
+	// attempt, with the backoff capped at one minute, before being handed
+	// to Fallback, OnDeadLetter and OnError.
+	RetryFor time.Duration
+
+	// MaxRetryBytes bounds the size of the batches being retried at the
+	// same time, so memory stays bounded during long outages with
+	// Concurrency. Batches failing when the limit is reached are not
+	// retried.
+	// By default there is no limit.
+	MaxRetryBytes int
+
+	// Metrics, if set, is notified of the hook activity.
+	Metrics Metrics
+
+	// BreakerThreshold sets how many batches must fail in a row to open the
+	// circuit breaker, which stops sending requests for BreakerCooldown.
+	// A negative value disables the circuit breaker.
+	// By default is 5.
+	BreakerThreshold int
+
+	// BreakerCooldown sets how long the circuit breaker stays open before
+	// trying a new request.
+	// By default is 30 seconds.
+	BreakerCooldown time.Duration
+
+	// Fallback, if set, receives a copy of every entry that is going to be
+	// lost: discarded because the queue is full or too large, or part of a
+	// batch that couldn't be delivered. Entries are written one per line.
+	Fallback io.Writer
+
+	// OnDeadLetter is called from the sender goroutines with every batch
+	// that couldn't be delivered, so it can be persisted elsewhere or
+	// replayed later.
+	OnDeadLetter func(DeadLetter)
+
+	// MaxDeadLetters sets how many undeliverable batches are retained in
+	// memory for DeadLetters. When the limit is reached the oldest one is
+	// discarded.
+	// By default no batch is retained.
+	MaxDeadLetters int
+
+	// Spool, if set, keeps a persistent copy of the entries until they are
+	// delivered. Failed batches are left in the spool and sent again the
+	// next time a hook is created with it.
+	Spool Spool
+
+	// Offline, if set, enables the offline mode: after repeated network
+	// failures batches are kept in a backlog instead of being retried, and
+	// sent once the intake is reachable again.
+	Offline *Offline
+
+	// Router, if set, picks the Destination of every entry, to send them to
+	// different Datadog organizations. Each destination has its own queue
+	// and is configured by the same Opts, except for Spool which is only
+	// used by the default one.
+	Router Router
+
+	// Mirrors are destinations receiving a copy of every entry, such as the
+	// intake of a new organization during a migration. Like the ones of
+	// Router, each of them has its own queue, retries and Stats, see
+	// Hook.Mirrors.
+	Mirrors []Destination
+
+	// CloseOnExit registers the hook as a logrus exit handler, so Fatal
+	// and logrus.Exit close it, delivering the last entries, before the
+	// process terminates. Exit handlers can't be removed, the hook stays
+	// registered even after Close.
+	CloseOnExit bool
 }
 
 // New creates a new Hook using the API key provided.
 // Optionally, opts can be provided for some performance tuning.
 func New(apiKey string, opts Opts) *Hook {
+	opts = withDefaults(opts)
+
+	d := &Hook{
+		client:   NewClient(apiKey, opts),
+		opts:     opts,
+		denied:   newFieldSet(opts.DenyFields),
+		allowed:  newFieldSet(opts.AllowFields),
+		minLevel: uint32(logrus.TraceLevel),
+	}
+
+	d.sampleRates.Store(opts.SampleRates)
+
+	d.priorities = levelSet(opts.PriorityLevels)
+	d.events = levelSet(opts.EventLevels)
+	d.startEvents()
+
+	if opts.AdaptiveSampling != nil {
+		d.adaptive = newAdaptiveSampler(*opts.AdaptiveSampling, opts.Clock)
+	}
+
+	if opts.RuntimeStats {
+		d.runtime = newRuntimeStats()
+	}
+
+	if opts.DedupWindow > 0 {
+		d.deduper = newDeduper(opts.DedupWindow)
+	}
+
+	for _, dest := range opts.Mirrors {
+		d.mirrors = append(d.mirrors, d.newDestination(dest))
+	}
+
+	if opts.CloseOnExit {
+		// run before the handlers registered earlier, which may exit on
+		// their own
+		logrus.DeferExitHandler(func() {
+			d.Close()
+		})
+	}
+
+	return d
+}
+
+// withDefaults returns opts with the default values filled in.
+func withDefaults(opts Opts) Opts {
 	if opts.FlushPeriod == 0 {
 		opts.FlushPeriod = 30 * time.Second
 	}
@@ -55,8 +518,77 @@ func New(apiKey string, opts Opts) *Hook {
 		opts.MaxBatchSize = 30
 	}
 
+	if opts.MaxBatchBytes == 0 {
+		opts.MaxBatchBytes = 5 * 1024 * 1024
+	}
+
+	if opts.MaxEntriesPerRequest == 0 {
+		opts.MaxEntriesPerRequest = 1000
+	}
+
+	if opts.MaxEntryBytes == 0 {
+		opts.MaxEntryBytes = 1024 * 1024
+	}
+
+	if opts.QueueSize == 0 {
+		opts.QueueSize = 1000
+	}
+
+	if opts.Watermarks != nil {
+		w := opts.Watermarks.withDefaults()
+		opts.Watermarks = &w
+	}
+
+	if opts.Offline != nil {
+		o := opts.Offline.withDefaults()
+		opts.Offline = &o
+	}
+
+	if opts.Site == "" {
+		opts.Site = "datadoghq.eu"
+	}
+
+	if opts.EventsURL == "" {
+		opts.EventsURL = "https://api." + opts.Site + "/api/v1/events"
+	}
+
 	if opts.PostURL == "" {
-		opts.PostURL = "https://http-intake.logs.datadoghq.eu/v1/input"
+		opts.PostURL = "https://http-intake.logs." + opts.Site + "/v1/input"
+	}
+
+	if opts.Hostname == "" {
+		opts.Hostname = os.Getenv("DD_HOSTNAME")
+	}
+
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+
+	if opts.TCPAddr == "" {
+		opts.TCPAddr = tcpIntake(opts.Site)
+		if opts.Protocol == ProtocolAgent {
+			opts.TCPAddr = agentAddr
+		}
+	}
+
+	if opts.Levels == nil {
+		opts.Levels = logrus.AllLevels
+	}
+
+	if opts.SampleRates != nil {
+		opts.SampleRates = copyRates(opts.SampleRates)
+	}
+
+	if opts.StackExtractor == nil {
+		opts.StackExtractor = PkgErrorsStack
+	}
+
+	if opts.Formatter == nil && opts.BodyFormat == PlainText {
+		opts.Formatter = plainFormatter{}
+	}
+
+	if opts.Formatter == nil && opts.Marshal != nil {
+		opts.Formatter = marshalFormatter{marshal: opts.Marshal}
 	}
 
 	if opts.Formatter == nil {
@@ -69,100 +601,207 @@ func New(apiKey string, opts Opts) *Hook {
 		}
 	}
 
-	d := &Hook{
-		key:   apiKey,
-		opts:  opts,
-		batch: make(chan []byte, opts.MaxBatchSize),
+	if opts.DisableStatus {
+		opts.Statuses = nil
+	} else if opts.Statuses == nil {
+		opts.Statuses = DatadogStatuses
 	}
-	d.timer = time.AfterFunc(opts.FlushPeriod, func() {
-		d.Flush()
-	})
-
-	return d
-}
 
-// Fire is automatically called by logrus everytime a log entry is created.
-func (d *Hook) Fire(entry *logrus.Entry) error {
-	// format entry into json []byte
-	result, err := d.opts.Formatter.Format(entry)
-	if err != nil {
-		return err
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
 	}
 
-	// add entry to batch
-	d.batch <- result
+	if opts.RequestTimeout == 0 {
+		opts.RequestTimeout = 10 * time.Second
+	}
 
-	// if batch is big enough, flush it
-	if len(d.batch) == cap(d.batch) {
-		d.Flush()
+	if opts.Concurrency == 0 {
+		opts.Concurrency = 1
 	}
 
-	return err
-}
+	if opts.RetryBackoff == 0 {
+		opts.RetryBackoff = time.Second
+	}
 
-// Levels is called by logrus to check what levels are handler by this hook.
-func (d *Hook) Levels() []logrus.Level {
-	return logrus.AllLevels
-}
+	if opts.BreakerThreshold == 0 {
+		opts.BreakerThreshold = 5
+	}
 
-// Flush flushes the current batch of log entries, sending them to Datadog
-// server.
-func (d *Hook) Flush() error {
-	currentBatch := d.batch
-	d.batch = make(chan []byte, d.opts.MaxBatchSize)
+	if opts.BreakerCooldown == 0 {
+		opts.BreakerCooldown = 30 * time.Second
+	}
 
-	close(currentBatch)
+	if opts.Metrics == nil {
+		opts.Metrics = nopMetrics{}
+	}
 
-	d.lastFlush = time.Now()
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
 
-	// prepare json body
-	buffer := new(bytes.Buffer)
+	if opts.MaxIdleConnsPerHost == 0 {
+		opts.MaxIdleConnsPerHost = opts.Concurrency
+		if opts.MaxIdleConnsPerHost < 2 {
+			opts.MaxIdleConnsPerHost = 2
+		}
+	}
 
-	_, err := buffer.WriteString("[")
-	if err != nil {
-		return err
+	if opts.IdleConnTimeout == 0 {
+		opts.IdleConnTimeout = 90 * time.Second
 	}
 
-	for log := range currentBatch {
-		_, err := buffer.Write(log)
-		if err != nil {
-			return err
+	if opts.HTTPClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+		transport.Proxy = http.ProxyFromEnvironment
+		if opts.ProxyURL != nil {
+			transport.Proxy = http.ProxyURL(opts.ProxyURL)
+		}
+
+		if opts.TLSConfig != nil {
+			transport.TLSClientConfig = opts.TLSConfig.Clone()
 		}
 
-		// if there are still elements, a trailing comma is needed to separate
-		// them
-		if len(currentBatch) > 0 {
-			_, err = buffer.WriteRune(',')
-			if err != nil {
-				return err
+		// HTTP/2 is attempted even with a custom TLSConfig or dialer
+		transport.ForceAttemptHTTP2 = !opts.DisableHTTP2
+		if opts.DisableHTTP2 {
+			transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		}
+
+		if opts.UnixSocketPath != "" {
+			path := opts.UnixSocketPath
+			dialer := &net.Dialer{}
+			transport.Proxy = nil
+			transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", path)
 			}
 		}
+
+		opts.HTTPClient = &http.Client{Transport: transport}
 	}
 
-	buffer.WriteString("]")
+	return opts
+}
 
-	// prepare http request
-	req, err := http.NewRequest("POST", d.opts.PostURL, buffer)
-	if err != nil {
-		return err
+// Fire is automatically called by logrus everytime a log entry is created.
+// The entry is formatted and put in the queue, the actual sending happens in
+// background. Entries logged with a context returned by Skip are ignored.
+func (d *Hook) Fire(entry *logrus.Entry) (err error) {
+	if skipped(entry) {
+		return nil
+	}
+
+	defer d.recoverFire(&err)
+
+	if entry.Level > logrus.Level(atomic.LoadUint32(&d.minLevel)) {
+		return nil
+	}
+
+	if d.dedup(entry) {
+		return nil
+	}
+
+	keep, rate := d.sample(entry.Level)
+	if !keep {
+		return nil
 	}
 
-	req.Header.Set("DD-API-KEY", d.key)
-	req.Header.Set("Content-Type", "application/json")
+	return d.enqueue(entry, rate)
+}
 
-	// do request
-	client := &http.Client{}
+// enqueue processes entry, kept by sampling at rate, and puts it in the
+// queue of its destination and of the mirrors.
+func (d *Hook) enqueue(entry *logrus.Entry, rate float64) error {
+	prepared := d.prepare(entry, rate)
+	if prepared == nil {
+		return nil
+	}
+	if prepared != entry && len(d.opts.Transformers) == 0 {
+		defer releaseEntry(prepared)
+	}
+	entry = prepared
 
-	_, err = client.Do(req)
+	// format entry into json []byte
+	result, err := d.format(entry)
 	if err != nil {
 		return err
 	}
 
-	d.scheduleFlush()
+	result = d.fit(entry, result)
+
+	d.sendEvent(entry)
+
+	enqueue := d.enqueuer(entry.Level)
 
-	return nil
+	err = enqueue(d.route(entry), result)
+	for _, c := range d.mirrors {
+		if e := enqueue(c, result); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
 }
 
-func (d *Hook) scheduleFlush() {
-	d.timer.Reset(d.opts.FlushPeriod)
+// Levels is called by logrus to check what levels are handler by this hook.
+func (d *Hook) Levels() []logrus.Level {
+	return d.opts.Levels
+}
+
+// Client returns the Client used by the hook to deliver entries to its
+// default destination. It can be used to send records that don't come from
+// logrus through the same queue, see the slogdd subpackage.
+func (d *Hook) Client() *Client {
+	return d.client
+}
+
+// Mirrors returns the clients delivering to Opts.Mirrors, in the same
+// order, to inspect their Stats.
+func (d *Hook) Mirrors() []*Client {
+	return d.mirrors
+}
+
+// Flush sends every queued log entry to Datadog server, waiting for the
+// requests to complete.
+func (d *Hook) Flush() error {
+	return d.FlushContext(context.Background())
+}
+
+// FlushContext is like Flush, but stops waiting when ctx is done, see
+// Client.FlushContext.
+func (d *Hook) FlushContext(ctx context.Context) error {
+	err := d.client.FlushContext(ctx)
+
+	for _, c := range append(d.routed(), d.mirrors...) {
+		if e := c.FlushContext(ctx); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// Close flushes the pending entries and stops the hook. Any request still
+// in flight after that is cancelled.
+// The hook must not be used after Close.
+func (d *Hook) Close() error {
+	return d.CloseContext(context.Background())
+}
+
+// CloseContext is like Close, but gives up delivering the pending entries
+// when ctx is done, see Client.CloseContext.
+func (d *Hook) CloseContext(ctx context.Context) error {
+	d.closeDedup()
+	d.closeEvents(ctx)
+
+	err := d.client.CloseContext(ctx)
+
+	for _, c := range append(d.closeRoutes(), d.mirrors...) {
+		if e := c.CloseContext(ctx); e != nil {
+			err = e
+		}
+	}
+
+	return err
 }
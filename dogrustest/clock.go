@@ -0,0 +1,153 @@
+// Package dogrustest provides utilities for testing code logging through
+// dogrus, deterministically and without reaching Datadog.
+//
+// Clock drives the time of a hook, so flushes happen when the test says so:
+//
+//	clock := dogrustest.NewClock(time.Now())
+//	hook := dogrus.New(apiKey, dogrus.Opts{Clock: clock})
+//	...
+//	clock.Advance(30 * time.Second) // triggers the periodic flush
+package dogrustest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+)
+
+// Clock is a dogrus.Clock whose time only moves with Advance.
+// It's safe for concurrent use.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+// NewClock returns a Clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now implements dogrus.Clock.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer implements dogrus.Clock.
+func (c *Clock) NewTimer(d time.Duration) dogrus.Timer {
+	t := &timer{clock: c, ch: make(chan time.Time, 1)}
+	t.Reset(d)
+
+	return t
+}
+
+// AfterFunc implements dogrus.Clock.
+func (c *Clock) AfterFunc(d time.Duration, f func()) dogrus.Timer {
+	t := &timer{clock: c, f: f}
+	t.Reset(d)
+
+	return t
+}
+
+// Advance moves the time forward by d, firing the timers expiring in the
+// meantime, in order. Functions given to AfterFunc are run in their own
+// goroutine, like time.AfterFunc does.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+
+	for {
+		sort.SliceStable(c.timers, func(i, j int) bool {
+			return c.timers[i].when.Before(c.timers[j].when)
+		})
+
+		if len(c.timers) == 0 || c.timers[0].when.After(end) {
+			break
+		}
+
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		c.now = t.when
+		t.fire(c.now)
+	}
+
+	c.now = end
+	c.mu.Unlock()
+}
+
+// Timers returns the number of timers waiting to fire. Tests can poll it to
+// know when the code under test is waiting on the clock, for example when a
+// retry backoff has started.
+func (c *Clock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// remove takes t out of the waiting timers, telling if it was there.
+// The caller holds c.mu.
+func (c *Clock) remove(t *timer) bool {
+	for i, other := range c.timers {
+		if other == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// timer is a dogrus.Timer created by a Clock.
+type timer struct {
+	clock *Clock
+	when  time.Time
+	ch    chan time.Time
+	f     func()
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.clock.remove(t)
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.clock.remove(t)
+	t.when = t.clock.now.Add(d)
+
+	if d <= 0 {
+		t.fire(t.clock.now)
+	} else {
+		t.clock.timers = append(t.clock.timers, t)
+	}
+
+	return active
+}
+
+// fire delivers the expiration of the timer. The caller holds clock.mu.
+func (t *timer) fire(now time.Time) {
+	if t.f != nil {
+		go t.f()
+		return
+	}
+
+	// like time.Timer, a tick not received yet is not replaced
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
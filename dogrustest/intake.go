@@ -0,0 +1,292 @@
+package dogrustest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Entry is a log entry received by an Intake, decoded from JSON. Entries of
+// PlainText bodies only have the "message" key.
+type Entry map[string]interface{}
+
+// Batch is a request received by an Intake.
+type Batch struct {
+	// Header is the header of the request.
+	Header http.Header
+
+	// Query is the query string of the request, carrying the metadata of
+	// PlainText bodies.
+	Query string
+
+	// Entries are the entries carried by the request.
+	Entries []Entry
+}
+
+// Intake is a fake Datadog logs intake, recording the batches it receives.
+// Requests missing the API key or carrying a malformed body are rejected
+// with 400, and reported as test errors when the Intake is closed.
+//
+//	intake := dogrustest.NewIntake(t, "api-key")
+//	hook := dogrus.New("api-key", dogrus.Opts{PostURL: intake.URL})
+//	logger.AddHook(hook)
+//	logger.Info("hello")
+//	hook.Flush()
+//	intake.AssertEntry(t, "message", "hello")
+type Intake struct {
+	// URL is the address to use as dogrus.Opts.PostURL.
+	URL string
+
+	tb     testing.TB
+	apiKey string
+	server *httptest.Server
+
+	mu       sync.Mutex
+	batches  []Batch
+	statuses []int
+	errs     []error
+	received chan struct{}
+}
+
+// NewIntake starts an Intake expecting apiKey in the DD-API-KEY header,
+// unless it's empty. It's closed when the test ends, see Close.
+func NewIntake(tb testing.TB, apiKey string) *Intake {
+	tb.Helper()
+
+	in := &Intake{
+		tb:       tb,
+		apiKey:   apiKey,
+		received: make(chan struct{}, 1),
+	}
+
+	in.server = httptest.NewServer(http.HandlerFunc(in.serve))
+	in.URL = in.server.URL + "/api/v2/logs"
+	tb.Cleanup(in.Close)
+
+	return in
+}
+
+// Close stops the Intake, failing the test with the requests rejected since
+// it started. It's called when the test ends, but tests can call it earlier,
+// from the test goroutine, to check the requests made so far.
+func (in *Intake) Close() {
+	in.tb.Helper()
+	in.server.Close()
+
+	in.mu.Lock()
+	errs := in.errs
+	in.errs = nil
+	in.mu.Unlock()
+
+	for _, err := range errs {
+		in.tb.Errorf("dogrustest: %v", err)
+	}
+}
+
+// Fail makes the next requests fail, responding with the given statuses in
+// order, such as http.StatusTooManyRequests or http.StatusServiceUnavailable.
+// Failed requests are not recorded.
+func (in *Intake) Fail(statuses ...int) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.statuses = append(in.statuses, statuses...)
+}
+
+// Batches returns the batches received so far.
+func (in *Intake) Batches() []Batch {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	return append([]Batch(nil), in.batches...)
+}
+
+// Entries returns the entries received so far, in order.
+func (in *Intake) Entries() []Entry {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	var entries []Entry
+	for _, b := range in.batches {
+		entries = append(entries, b.Entries...)
+	}
+
+	return entries
+}
+
+// Reset forgets the batches received so far and the pending failures.
+func (in *Intake) Reset() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.batches = nil
+	in.statuses = nil
+}
+
+// WaitForEntries waits until at least n entries have been received, failing
+// the test if it takes longer than timeout. It returns the entries received.
+func (in *Intake) WaitForEntries(tb testing.TB, n int, timeout time.Duration) []Entry {
+	tb.Helper()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		entries := in.Entries()
+		if len(entries) >= n {
+			return entries
+		}
+
+		select {
+		case <-in.received:
+		case <-deadline.C:
+			tb.Fatalf("dogrustest: received %d entries, expecting %d", len(entries), n)
+			return entries
+		}
+	}
+}
+
+// AssertEntry fails the test unless an entry has been received with key set
+// to value. Numbers are decoded as float64.
+func (in *Intake) AssertEntry(tb testing.TB, key string, value interface{}) Entry {
+	tb.Helper()
+
+	for _, e := range in.Entries() {
+		if v, ok := e[key]; ok && reflect.DeepEqual(v, value) {
+			return e
+		}
+	}
+
+	tb.Errorf("dogrustest: no entry with %s=%v", key, value)
+	return nil
+}
+
+// AssertNoEntry fails the test if an entry has been received with key set
+// to value.
+func (in *Intake) AssertNoEntry(tb testing.TB, key string, value interface{}) {
+	tb.Helper()
+
+	for _, e := range in.Entries() {
+		if v, ok := e[key]; ok && reflect.DeepEqual(v, value) {
+			tb.Errorf("dogrustest: unexpected entry with %s=%v", key, value)
+			return
+		}
+	}
+}
+
+func (in *Intake) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		// warm up requests
+		return
+	}
+
+	in.mu.Lock()
+	if len(in.statuses) > 0 {
+		status := in.statuses[0]
+		in.statuses = in.statuses[1:]
+		in.mu.Unlock()
+
+		w.WriteHeader(status)
+		return
+	}
+	in.mu.Unlock()
+
+	entries, err := in.decode(r)
+	if err != nil {
+		// testing.TB must not be used from the server goroutines, the
+		// errors are reported by Close
+		in.mu.Lock()
+		in.errs = append(in.errs, err)
+		in.mu.Unlock()
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	in.mu.Lock()
+	in.batches = append(in.batches, Batch{
+		Header:  r.Header.Clone(),
+		Query:   r.URL.RawQuery,
+		Entries: entries,
+	})
+	in.mu.Unlock()
+
+	select {
+	case in.received <- struct{}{}:
+	default:
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// decode validates the request and returns the entries it carries.
+func (in *Intake) decode(r *http.Request) ([]Entry, error) {
+	if r.Method != http.MethodPost {
+		return nil, fmt.Errorf("unexpected method %s", r.Method)
+	}
+
+	if in.apiKey != "" && r.Header.Get("DD-API-KEY") != in.apiKey {
+		return nil, fmt.Errorf("unexpected API key %q", r.Header.Get("DD-API-KEY"))
+	}
+
+	var body io.Reader = r.Body
+	switch enc := r.Header.Get("Content-Encoding"); enc {
+	case "":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		body = gz
+	default:
+		return nil, fmt.Errorf("unexpected Content-Encoding %q", enc)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	var entries []Entry
+	switch ct := r.Header.Get("Content-Type"); ct {
+	case "application/json":
+		err = json.Unmarshal(data, &entries)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+
+	case "application/x-ndjson":
+		s := bufio.NewScanner(bytes.NewReader(data))
+		s.Buffer(nil, len(data)+1)
+		for s.Scan() {
+			var e Entry
+			if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+				return nil, fmt.Errorf("invalid NDJSON line: %w", err)
+			}
+			entries = append(entries, e)
+		}
+
+	case "text/plain":
+		for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+			entries = append(entries, Entry{"message": string(line)})
+		}
+
+	default:
+		return nil, fmt.Errorf("unexpected Content-Type %q", ct)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("empty batch")
+	}
+
+	return entries, nil
+}
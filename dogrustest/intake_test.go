@@ -0,0 +1,53 @@
+package dogrustest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// recorder is a testing.TB recording the errors instead of failing the
+// test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+func TestIntakeReportsRejectedRequestsOnClose(t *testing.T) {
+	tb := &recorder{TB: t}
+	in := NewIntake(tb, "key")
+
+	req, err := http.NewRequest(http.MethodPost, in.URL, strings.NewReader(`[{"message":"hello"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status is %d, want 400", res.StatusCode)
+	}
+	if len(tb.errors) != 0 {
+		t.Fatal("the error has been reported from the server goroutine")
+	}
+
+	in.Close()
+	if len(tb.errors) != 1 {
+		t.Fatalf("reported %d errors, want 1", len(tb.errors))
+	}
+
+	// closing again, as the cleanup does, doesn't report it twice
+	in.Close()
+	if len(tb.errors) != 1 {
+		t.Fatalf("reported %d errors, want 1", len(tb.errors))
+	}
+}
@@ -0,0 +1,100 @@
+package dogstatsd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiSeries is a metric in the payloads of the Datadog metrics API.
+type apiSeries struct {
+	Metric   string          `json:"metric"`
+	Type     string          `json:"type,omitempty"`
+	Interval int64           `json:"interval,omitempty"`
+	Points   [][]interface{} `json:"points"`
+	Tags     []string        `json:"tags,omitempty"`
+}
+
+// post sends the aggregated metrics to the Datadog metrics API: counts and
+// gauges as series, distributions as distribution points.
+func (c *Counter) post(values map[series]float64, samples map[series][]float64) error {
+	now := time.Now().Unix()
+
+	var points, distributions []apiSeries
+	for s, v := range values {
+		p := apiSeries{
+			Metric:   s.metric,
+			Type:     "gauge",
+			Interval: int64(c.opts.FlushPeriod / time.Second),
+			Points:   [][]interface{}{{now, v}},
+			Tags:     c.tags(s),
+		}
+		if s.kind == Count {
+			p.Type = "count"
+		}
+		points = append(points, p)
+	}
+
+	for s, vs := range samples {
+		distributions = append(distributions, apiSeries{
+			Metric: s.metric,
+			Points: [][]interface{}{{now, vs}},
+			Tags:   c.tags(s),
+		})
+	}
+
+	var err error
+	if len(points) > 0 {
+		err = c.postJSON("/api/v1/series", points)
+	}
+
+	if len(distributions) > 0 {
+		if e := c.postJSON("/api/v1/distribution_points", distributions); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// postJSON posts s to path of the metrics API.
+func (c *Counter) postJSON(path string, s []apiSeries) error {
+	body, err := json.Marshal(map[string][]apiSeries{"series": s})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.opts.APIURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", c.opts.APIKey)
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("dogstatsd: metrics API responded %s", res.Status)
+	}
+
+	return nil
+}
+
+// tags returns the tags of s, including Options.Tags.
+func (c *Counter) tags(s series) []string {
+	tags := append([]string(nil), c.opts.Tags...)
+	if s.tags != "" {
+		tags = append(tags, strings.Split(s.tags, ",")...)
+	}
+
+	return tags
+}
@@ -0,0 +1,318 @@
+// Package dogstatsd counts log entries by level as DogStatsD metrics, so
+// error rate spikes can be alerted on even when log indexing lags behind,
+// and derives further metrics from the entries with rules.
+//
+// The Counter is a logrus hook of its own, so it counts every entry of the
+// logger, including the ones left out by sampling or deduplication:
+//
+//	counter, err := dogstatsd.New(&dogstatsd.Options{Service: "checkout"})
+//	if err != nil {
+//		// ...
+//	}
+//	defer counter.Close()
+//	logrus.AddHook(counter)
+//	logrus.AddHook(hook)
+//
+// Counts are aggregated in memory and sent over UDP to the Datadog Agent
+// every FlushPeriod, tagged by level and service, as app.logs.count by
+// default.
+//
+// Rules derive metrics from the fields of the entries, for example the
+// distribution of the request latencies and a count of the failed payments:
+//
+//	counter, err := dogstatsd.New(&dogstatsd.Options{
+//		Rules: []dogstatsd.Rule{{
+//			Metric:    "http.request.latency",
+//			Kind:      dogstatsd.Distribution,
+//			Field:     "latency_ms",
+//			TagFields: []string{"route"},
+//		}, {
+//			Metric: "payments.failed",
+//			Match:  dogstatsd.FieldEquals("payment.status", "failed"),
+//		}},
+//	})
+//
+// With an APIKey, metrics are posted to the Datadog metrics API instead,
+// for hosts without an Agent.
+package dogstatsd
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+)
+
+// Options configures a Counter.
+type Options struct {
+	// Addr is the host:port address of the DogStatsD server.
+	// By default is DD_AGENT_HOST:DD_DOGSTATSD_PORT, falling back to
+	// localhost:8125.
+	Addr string
+
+	// Metric is the name of the counter of entries.
+	// By default is app.logs.count.
+	Metric string
+
+	// DisableCount disables the counter of entries, leaving only the
+	// metrics of Rules.
+	DisableCount bool
+
+	// Rules derive further metrics from the entries.
+	Rules []Rule
+
+	// Service is the value of the service tag, unless the entry has a
+	// service field of its own, see dogrus.ServiceKey and
+	// dogrus.OverrideServiceKey.
+	Service string
+
+	// Tags are attached to every metric, in key:value format.
+	// Besides them, metrics are always tagged by level and service.
+	Tags []string
+
+	// Levels are the levels of the entries to count.
+	// By default are all levels.
+	Levels []logrus.Level
+
+	// FlushPeriod is how often metrics are sent.
+	// By default is 10 seconds, the DogStatsD flush interval.
+	FlushPeriod time.Duration
+
+	// APIKey, when set, makes the counter post metrics to the Datadog
+	// metrics API instead of sending them to the DogStatsD server at Addr.
+	APIKey string
+
+	// APIURL is the base URL of the Datadog metrics API.
+	// By default is https://api.datadoghq.eu, like the intake of dogrus.
+	APIURL string
+}
+
+// Counter is a logrus hook counting entries by level and service, and
+// deriving the metrics of Options.Rules.
+// Use New() to create a new counter.
+type Counter struct {
+	opts   Options
+	rules  []Rule
+	conn   net.Conn
+	client *http.Client
+
+	mu      sync.Mutex
+	values  map[series]float64
+	samples map[series][]float64
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates a Counter sending to the DogStatsD server of opts, which can
+// be nil to use the defaults.
+func New(opts *Options) (*Counter, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.Addr == "" {
+		o.Addr = defaultAddr()
+	}
+
+	if o.Metric == "" {
+		o.Metric = "app.logs.count"
+	}
+
+	if o.Levels == nil {
+		o.Levels = logrus.AllLevels
+	}
+
+	if o.FlushPeriod == 0 {
+		o.FlushPeriod = 10 * time.Second
+	}
+
+	if o.APIURL == "" {
+		o.APIURL = "https://api.datadoghq.eu"
+	}
+
+	c := &Counter{
+		opts:    o,
+		values:  make(map[series]float64),
+		samples: make(map[series][]float64),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if !o.DisableCount {
+		c.rules = append(c.rules, Rule{Metric: o.Metric})
+	}
+	c.rules = append(c.rules, o.Rules...)
+
+	if o.APIKey != "" {
+		c.client = &http.Client{Timeout: 10 * time.Second}
+	} else {
+		conn, err := net.Dial("udp", o.Addr)
+		if err != nil {
+			return nil, err
+		}
+		c.conn = conn
+	}
+
+	go c.run()
+
+	return c, nil
+}
+
+// defaultAddr returns the address of the agent from the environment, like
+// the Datadog clients do.
+func defaultAddr() string {
+	host := os.Getenv("DD_AGENT_HOST")
+	if host == "" {
+		host = "localhost"
+	}
+
+	port := os.Getenv("DD_DOGSTATSD_PORT")
+	if port == "" {
+		port = "8125"
+	}
+
+	return net.JoinHostPort(host, port)
+}
+
+// Levels implements logrus.Hook.
+func (c *Counter) Levels() []logrus.Level {
+	return c.opts.Levels
+}
+
+// Fire implements logrus.Hook, measuring entry.
+func (c *Counter) Fire(entry *logrus.Entry) error {
+	service := c.opts.Service
+	for _, field := range []string{dogrus.ServiceKey, dogrus.OverrideServiceKey} {
+		if s, ok := entry.Data[field].(string); ok && s != "" {
+			service = s
+		}
+	}
+
+	tags := make([]string, 1, 2)
+	tags[0] = "level:" + entry.Level.String()
+	if service != "" {
+		tags = append(tags, "service:"+tagValue(service))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.rules {
+		c.measure(&c.rules[i], entry, tags[:len(tags):len(tags)])
+	}
+
+	return nil
+}
+
+// Flush sends the metrics accumulated so far.
+func (c *Counter) Flush() error {
+	c.mu.Lock()
+	values, samples := c.values, c.samples
+	c.values = make(map[series]float64, len(values))
+	c.samples = make(map[series][]float64, len(samples))
+	c.mu.Unlock()
+
+	if len(values) == 0 && len(samples) == 0 {
+		return nil
+	}
+
+	if c.client != nil {
+		return c.post(values, samples)
+	}
+
+	var lines []string
+	for s, v := range values {
+		lines = append(lines, c.line(s, v))
+	}
+	for s, vs := range samples {
+		for _, v := range vs {
+			lines = append(lines, c.line(s, v))
+		}
+	}
+	sort.Strings(lines)
+
+	// datagrams must stay below the usual MTU
+	var err error
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() > 0 && buf.Len()+len(line)+1 > 1432 {
+			if _, e := c.conn.Write(buf.Bytes()); e != nil {
+				err = e
+			}
+			buf.Reset()
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+	}
+
+	if _, e := c.conn.Write(buf.Bytes()); e != nil {
+		err = e
+	}
+
+	return err
+}
+
+// line returns the DogStatsD datagram line of value v for s.
+func (c *Counter) line(s series, v float64) string {
+	typ := "c"
+	switch s.kind {
+	case Gauge:
+		typ = "g"
+	case Distribution:
+		typ = "d"
+	}
+
+	return s.metric + ":" + strconv.FormatFloat(v, 'f', -1, 64) + "|" + typ + "|#" + strings.Join(c.tags(s), ",")
+}
+
+// Close sends the last metrics and stops the counter.
+// The counter must not be used after Close.
+func (c *Counter) Close() error {
+	var err error
+
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		<-c.done
+
+		err = c.Flush()
+		if c.conn == nil {
+			return
+		}
+		if e := c.conn.Close(); e != nil && err == nil {
+			err = e
+		}
+	})
+
+	return err
+}
+
+// run flushes the metrics every FlushPeriod until the counter is closed.
+func (c *Counter) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.opts.FlushPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Flush()
+		case <-c.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package dogstatsd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+)
+
+// newAgent returns a DogStatsD server for opts, and a logger with the
+// counter it configures.
+func newAgent(t *testing.T, opts Options) (net.PacketConn, *Counter, *logrus.Logger) {
+	t.Helper()
+
+	agent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { agent.Close() })
+
+	opts.Addr = agent.LocalAddr().String()
+	opts.FlushPeriod = time.Hour
+
+	counter, err := New(&opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { counter.Close() })
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+	logger.AddHook(counter)
+
+	return agent, counter, logger
+}
+
+// datagrams returns the datagrams received by agent until none arrives for
+// a while.
+func datagrams(t *testing.T, agent net.PacketConn) []string {
+	t.Helper()
+
+	var got []string
+	buf := make([]byte, 65536)
+	for {
+		agent.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, _, err := agent.ReadFrom(buf)
+		if err != nil {
+			return got
+		}
+		got = append(got, string(buf[:n]))
+	}
+}
+
+func TestCounter(t *testing.T) {
+	agent, counter, logger := newAgent(t, Options{
+		Service: "checkout",
+		Tags:    []string{"env:test"},
+		Levels:  []logrus.Level{logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel},
+	})
+
+	logger.Error("one")
+	logger.Error("two")
+	logger.Warn("three")
+	logger.WithField(dogrus.ServiceKey, "payments").Info("four")
+	logger.WithField(dogrus.OverrideServiceKey, "a,b|c").Info("five")
+	logger.Debug("not counted")
+
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"app.logs.count:1|c|#env:test,level:info,service:a_b_c",
+		"app.logs.count:1|c|#env:test,level:info,service:payments",
+		"app.logs.count:1|c|#env:test,level:warning,service:checkout",
+		"app.logs.count:2|c|#env:test,level:error,service:checkout",
+	}, "\n")
+	if got := datagrams(t, agent); len(got) != 1 || got[0] != want {
+		t.Fatalf("received %q, want %q", got, want)
+	}
+
+	// the counts restart from zero after a flush
+	logger.Error("six")
+	if err := counter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := datagrams(t, agent); len(got) != 1 || got[0] != "app.logs.count:1|c|#env:test,level:error,service:checkout" {
+		t.Fatalf("received %q after Close, want the last count", got)
+	}
+}
+
+func TestCounterSplitsDatagrams(t *testing.T) {
+	agent, counter, logger := newAgent(t, Options{Metric: "logs"})
+
+	for i := 0; i < 100; i++ {
+		logger.WithField(dogrus.ServiceKey, fmt.Sprintf("service-%03d", i)).Info("hello")
+	}
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := 0
+	got := datagrams(t, agent)
+	for _, d := range got {
+		if len(d) > 1432 {
+			t.Errorf("datagram of %d bytes, above the MTU", len(d))
+		}
+		lines += len(strings.Split(d, "\n"))
+	}
+	if len(got) < 2 || lines != 100 {
+		t.Fatalf("received %d lines in %d datagrams, want 100 in several", lines, len(got))
+	}
+}
+
+func TestDefaultAddr(t *testing.T) {
+	t.Setenv("DD_AGENT_HOST", "")
+	t.Setenv("DD_DOGSTATSD_PORT", "")
+	if addr := defaultAddr(); addr != "localhost:8125" {
+		t.Errorf("default address is %s", addr)
+	}
+
+	t.Setenv("DD_AGENT_HOST", "10.0.0.7")
+	t.Setenv("DD_DOGSTATSD_PORT", "9125")
+	if addr := defaultAddr(); addr != "10.0.0.7:9125" {
+		t.Errorf("address from the environment is %s", addr)
+	}
+}
@@ -0,0 +1,165 @@
+package dogstatsd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Kind is the type of a metric derived by a Rule.
+type Kind int
+
+const (
+	// Count sums the value of the entries, or counts them when the rule has
+	// no Field.
+	Count Kind = iota
+
+	// Gauge keeps the last value seen in each flush period.
+	Gauge
+
+	// Distribution sends every value, so that percentiles can be computed
+	// globally by Datadog.
+	Distribution
+)
+
+// maxSamples bounds the distribution values kept for a series between two
+// flushes, any further value is dropped.
+const maxSamples = 10000
+
+// Rule derives a metric from the entries it matches.
+type Rule struct {
+	// Metric is the name of the metric.
+	Metric string
+
+	// Kind is the type of the metric.
+	// By default is Count.
+	Kind Kind
+
+	// Field is the field holding the value of the metric. Entries without
+	// it, or with a value that isn't a number, are skipped.
+	// Can be empty for counts, to count the matching entries.
+	Field string
+
+	// Match tells if an entry must be measured.
+	// By default all entries are, see also HasField and FieldEquals.
+	Match func(*logrus.Entry) bool
+
+	// TagFields are the fields turned into tags, named after the field,
+	// when present in the entry.
+	TagFields []string
+
+	// Tags are attached to the metric, in addition to Options.Tags.
+	Tags []string
+}
+
+// HasField returns a Rule.Match function matching the entries that have
+// field key.
+func HasField(key string) func(*logrus.Entry) bool {
+	return func(e *logrus.Entry) bool {
+		_, ok := e.Data[key]
+		return ok
+	}
+}
+
+// FieldEquals returns a Rule.Match function matching the entries where
+// field key is equal to value.
+func FieldEquals(key string, value interface{}) func(*logrus.Entry) bool {
+	return func(e *logrus.Entry) bool {
+		v, ok := e.Data[key]
+		return ok && v == value
+	}
+}
+
+// series identifies an aggregated metric.
+type series struct {
+	metric string
+	kind   Kind
+	tags   string
+}
+
+// measure adds the value of entry to the series of rule, if the rule
+// matches it. The caller must hold c.mu.
+func (c *Counter) measure(rule *Rule, entry *logrus.Entry, tags []string) {
+	if rule.Match != nil && !rule.Match(entry) {
+		return
+	}
+
+	value := 1.0
+	if rule.Field != "" {
+		v, ok := number(entry.Data[rule.Field])
+		if !ok {
+			return
+		}
+		value = v
+	} else if rule.Kind != Count {
+		return
+	}
+
+	tags = append(tags, rule.Tags...)
+	for _, field := range rule.TagFields {
+		if v, ok := entry.Data[field]; ok {
+			tags = append(tags, field+":"+tagValue(v))
+		}
+	}
+
+	s := series{metric: rule.Metric, kind: rule.Kind, tags: strings.Join(tags, ",")}
+	switch rule.Kind {
+	case Count:
+		c.values[s] += value
+	case Gauge:
+		c.values[s] = value
+	case Distribution:
+		if len(c.samples[s]) < maxSamples {
+			c.samples[s] = append(c.samples[s], value)
+		}
+	}
+}
+
+// number returns v as a float64, if it's a number.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// tagValue returns v formatted as a tag value, without the characters
+// reserved by the DogStatsD protocol.
+func tagValue(v interface{}) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', '\n':
+			return '_'
+		}
+		return r
+	}, fmt.Sprint(v))
+}
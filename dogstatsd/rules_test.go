@@ -0,0 +1,170 @@
+package dogstatsd
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestRules(t *testing.T) {
+	agent, counter, logger := newAgent(t, Options{
+		DisableCount: true,
+		Rules: []Rule{{
+			Metric:    "http.request.latency",
+			Kind:      Distribution,
+			Field:     "latency_ms",
+			TagFields: []string{"route"},
+		}, {
+			Metric: "payments.failed",
+			Match:  FieldEquals("payment.status", "failed"),
+			Tags:   []string{"team:billing"},
+		}, {
+			Metric: "payments.amount",
+			Field:  "amount",
+			Match:  HasField("payment.status"),
+		}, {
+			Metric: "queue.depth",
+			Kind:   Gauge,
+			Field:  "depth",
+		}, {
+			// gauges and distributions need a value
+			Metric: "ignored",
+			Kind:   Gauge,
+		}},
+	})
+
+	logger.WithFields(logrus.Fields{"latency_ms": 12, "route": "/cart"}).Info("request")
+	logger.WithFields(logrus.Fields{"latency_ms": json.Number("30.5"), "route": "/cart"}).Info("request")
+	logger.WithFields(logrus.Fields{"latency_ms": "slow", "route": "/cart"}).Info("request")
+	logger.WithFields(logrus.Fields{"payment.status": "failed", "amount": 20}).Warn("payment")
+	logger.WithFields(logrus.Fields{"payment.status": "failed", "amount": uint8(5)}).Warn("payment")
+	logger.WithFields(logrus.Fields{"payment.status": "ok", "amount": 1.5}).Info("payment")
+	logger.WithField("depth", 7).Info("queue")
+	logger.WithField("depth", int64(3)).Info("queue")
+
+	if err := counter.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, d := range datagrams(t, agent) {
+		got = append(got, strings.Split(d, "\n")...)
+	}
+
+	want := []string{
+		"http.request.latency:12|d|#level:info,route:/cart",
+		"http.request.latency:30.5|d|#level:info,route:/cart",
+		"payments.amount:1.5|c|#level:info",
+		"payments.amount:25|c|#level:warning",
+		"payments.failed:2|c|#level:warning,team:billing",
+		"queue.depth:3|g|#level:info",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("received\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// metricsAPI records the series posted to the Datadog metrics API.
+type metricsAPI struct {
+	mu     sync.Mutex
+	series map[string][]apiSeries
+}
+
+func (m *metricsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("DD-API-KEY") != "key" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	var body struct{ Series []apiSeries }
+	b, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(b, &body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sort.Slice(body.Series, func(i, j int) bool { return body.Series[i].Metric < body.Series[j].Metric })
+	m.series[r.URL.Path] = append(m.series[r.URL.Path], body.Series...)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func TestRulesMetricsAPI(t *testing.T) {
+	api := &metricsAPI{series: make(map[string][]apiSeries)}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	counter, err := New(&Options{
+		APIKey:      "key",
+		APIURL:      srv.URL,
+		Service:     "checkout",
+		FlushPeriod: time.Minute,
+		Rules: []Rule{{
+			Metric: "http.request.latency",
+			Kind:   Distribution,
+			Field:  "latency_ms",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+	logger.AddHook(counter)
+
+	logger.WithField("latency_ms", 12).Info("request")
+	logger.WithField("latency_ms", 30).Info("request")
+
+	if err := counter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	series := api.series["/api/v1/series"]
+	if len(series) != 1 {
+		t.Fatalf("posted %d series, want the count", len(series))
+	}
+	count := series[0]
+	if count.Metric != "app.logs.count" || count.Type != "count" || count.Interval != 60 ||
+		count.Points[0][1] != float64(2) || !reflect.DeepEqual(count.Tags, []string{"level:info", "service:checkout"}) {
+		t.Errorf("posted %+v, want a count of 2", count)
+	}
+
+	distributions := api.series["/api/v1/distribution_points"]
+	if len(distributions) != 1 {
+		t.Fatalf("posted %d distributions, want the latency", len(distributions))
+	}
+	latency := distributions[0]
+	if latency.Metric != "http.request.latency" || !reflect.DeepEqual(latency.Points[0][1], []interface{}{float64(12), float64(30)}) {
+		t.Errorf("posted %+v, want the latencies", latency)
+	}
+}
+
+func TestRulesMetricsAPIError(t *testing.T) {
+	srv := httptest.NewServer(&metricsAPI{series: make(map[string][]apiSeries)})
+	defer srv.Close()
+
+	counter, err := New(&Options{APIKey: "wrong", APIURL: srv.URL, FlushPeriod: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	counter.Fire(&logrus.Entry{Data: logrus.Fields{}, Level: logrus.InfoLevel})
+
+	if err := counter.Close(); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Close returned %v, want the 403 of the metrics API", err)
+	}
+}
@@ -0,0 +1,47 @@
+package dogrus
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrMissingAPIKey is returned by NewFromEnv when DD_API_KEY is not set.
+var ErrMissingAPIKey = errors.New("dogrus: DD_API_KEY is not set")
+
+// NewFromEnv creates a new Hook configured by the standard Datadog
+// environment variables, like the other Datadog SDKs:
+//
+//	DD_API_KEY  the API key, required
+//	DD_SITE     Opts.Site
+//	DD_SERVICE  Opts.Service
+//	DD_HOSTNAME Opts.Hostname
+//	DD_ENV      Opts.Env
+//	DD_VERSION  Opts.Version
+//	DD_TAGS     Opts.Tags, separated by commas or spaces
+//
+// The given options are applied after the environment, so they take
+// precedence. The resulting Opts are validated like by NewWithError.
+func NewFromEnv(options ...Option) (*Hook, error) {
+	apiKey := os.Getenv("DD_API_KEY")
+	if apiKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	opts := Opts{
+		Site:     os.Getenv("DD_SITE"),
+		Service:  os.Getenv("DD_SERVICE"),
+		Hostname: os.Getenv("DD_HOSTNAME"),
+		Env:      os.Getenv("DD_ENV"),
+		Version:  os.Getenv("DD_VERSION"),
+		Tags: strings.FieldsFunc(os.Getenv("DD_TAGS"), func(r rune) bool {
+			return r == ',' || r == ' '
+		}),
+	}
+
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return NewWithError(apiKey, opts)
+}
@@ -0,0 +1,103 @@
+package dogrus
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Datadog standard attributes describing an error, used by Error Tracking.
+const (
+	ErrorMessageKey = "error.message"
+	ErrorKindKey    = "error.kind"
+	ErrorStackKey   = "error.stack"
+)
+
+// StackExtractor extracts the stack trace carried by an error.
+type StackExtractor interface {
+	// Stack returns the stack trace of err, if it has one.
+	Stack(err error) (string, bool)
+}
+
+// StackExtractorFunc is a function implementing StackExtractor.
+type StackExtractorFunc func(err error) (string, bool)
+
+// Stack implements StackExtractor.
+func (f StackExtractorFunc) Stack(err error) (string, bool) {
+	return f(err)
+}
+
+// PkgErrorsStack extracts stack traces from errors created by
+// github.com/pkg/errors, or any other error with a similar StackTrace method.
+// The whole chain of wrapped errors is inspected and the innermost stack
+// trace is used, since it's the closest to where the error happened.
+var PkgErrorsStack StackExtractor = StackExtractorFunc(pkgErrorsStack)
+
+func pkgErrorsStack(err error) (string, bool) {
+	stack, found := "", false
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		m := reflect.ValueOf(err).MethodByName("StackTrace")
+		if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+			continue
+		}
+
+		// pkg/errors stack traces print a frame per line with %+v
+		stack = strings.TrimPrefix(fmt.Sprintf("%+v", m.Call(nil)[0].Interface()), "\n")
+		found = true
+	}
+
+	return stack, found
+}
+
+// hasError tells if the error attributes can be added to entry.
+func (d *Hook) hasError(entry *logrus.Entry) bool {
+	if d.opts.DisableErrorAttributes {
+		return false
+	}
+
+	if _, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		return true
+	}
+
+	return d.opts.CaptureStack && entry.Level <= logrus.ErrorLevel
+}
+
+// mapError adds to e the Datadog error attributes describing its error
+// field, unless they are already there. The error field itself is removed
+// if it's missing from AllowFields, it was only kept to be mapped.
+func (d *Hook) mapError(e *logrus.Entry) {
+	if !d.hasError(e) {
+		return
+	}
+
+	if d.allowed != nil && !d.allowed.has(logrus.ErrorKey) {
+		defer delete(e.Data, logrus.ErrorKey)
+	}
+
+	if err, ok := e.Data[logrus.ErrorKey].(error); ok {
+		setDefault(e, ErrorMessageKey, err.Error())
+		setDefault(e, ErrorKindKey, fmt.Sprintf("%T", err))
+
+		if stack, ok := d.opts.StackExtractor.Stack(err); ok {
+			setDefault(e, ErrorStackKey, stack)
+		}
+	}
+
+	if d.opts.CaptureStack && e.Level <= logrus.ErrorLevel {
+		if _, ok := e.Data[ErrorStackKey]; !ok {
+			e.Data[ErrorStackKey] = string(debug.Stack())
+		}
+	}
+}
+
+// setDefault sets the field key of e, unless it's already set.
+func setDefault(e *logrus.Entry, key string, value interface{}) {
+	if _, ok := e.Data[key]; !ok {
+		e.Data[key] = value
+	}
+}
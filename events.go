@@ -0,0 +1,227 @@
+package dogrus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrEventsFull is reported to OnError for the events discarded because too
+// many of them are waiting to be posted.
+var ErrEventsFull = errors.New("dogrus: too many events pending")
+
+// eventQueueSize is how many events can wait to be posted.
+const eventQueueSize = 100
+
+// Limits of the Datadog Events API.
+const (
+	maxEventTitle          = 100
+	maxEventText           = 4000
+	maxEventAggregationKey = 100
+)
+
+// event is the body of a request to the Datadog Events API.
+type event struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	Tags           []string `json:"tags,omitempty"`
+	AlertType      string   `json:"alert_type"`
+	AggregationKey string   `json:"aggregation_key,omitempty"`
+	Host           string   `json:"host,omitempty"`
+	SourceTypeName string   `json:"source_type_name"`
+}
+
+// eventPoster posts the events of a hook in background.
+type eventPoster struct {
+	queue chan event
+	stop  chan struct{}
+	once  sync.Once
+	done  chan struct{}
+}
+
+// startEvents starts posting the events of Opts.EventLevels, if any.
+func (d *Hook) startEvents() {
+	if len(d.events) == 0 || d.opts.Sink != nil {
+		return
+	}
+
+	d.poster = &eventPoster{
+		queue: make(chan event, eventQueueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go d.postEvents()
+}
+
+// sendEvent posts a Datadog Event for e, if its level is in
+// Opts.EventLevels. Events are posted in background, except for fatal and
+// panic entries, which are posted before Fire returns since they are
+// followed by the end of the process.
+func (d *Hook) sendEvent(e *logrus.Entry) {
+	if d.poster == nil || !d.events[e.Level] {
+		return
+	}
+
+	ev := d.event(e)
+	if e.Level <= logrus.FatalLevel {
+		d.postEvent(ev)
+		return
+	}
+
+	select {
+	case d.poster.queue <- ev:
+	default:
+		d.eventFailed(ErrEventsFull)
+	}
+}
+
+// postEvents is the loop posting the queued events, until the hook is
+// closed. The events still queued then are posted before returning.
+func (d *Hook) postEvents() {
+	defer close(d.poster.done)
+
+	for {
+		select {
+		case ev := <-d.poster.queue:
+			d.postEvent(ev)
+		case <-d.poster.stop:
+			for n := len(d.poster.queue); n > 0; n-- {
+				d.postEvent(<-d.poster.queue)
+			}
+			return
+		}
+	}
+}
+
+// closeEvents posts the queued events, waiting for them until ctx is done.
+func (d *Hook) closeEvents(ctx context.Context) {
+	if d.poster == nil {
+		return
+	}
+
+	d.poster.once.Do(func() {
+		close(d.poster.stop)
+	})
+
+	select {
+	case <-d.poster.done:
+	case <-ctx.Done():
+	}
+}
+
+// eventFailed reports to OnError an event that couldn't be posted.
+func (d *Hook) eventFailed(err error) {
+	d.client.debugf("event not sent: %v", err)
+	if d.opts.OnError != nil {
+		d.opts.OnError(err, nil)
+	}
+}
+
+// event returns the Datadog Event describing e, tagged like its log.
+func (d *Hook) event(e *logrus.Entry) event {
+	ev := event{
+		Title:          shorten(e.Level.String()+": "+e.Message, maxEventTitle),
+		Text:           e.Message,
+		AlertType:      alertType(e.Level),
+		SourceTypeName: "go",
+	}
+
+	if stack, ok := e.Data[ErrorStackKey].(string); ok {
+		ev.Text += "\n\n" + stack
+	}
+	ev.Text = shorten(ev.Text, maxEventText)
+
+	if tags, ok := e.Data[TagsKey].(string); ok && tags != "" {
+		ev.Tags = strings.Split(tags, ",")
+	}
+
+	service, _ := e.Data[ServiceKey].(string)
+	if service != "" {
+		ev.Tags = append(ev.Tags, "service:"+service)
+	}
+
+	ev.Host, _ = e.Data[HostnameKey].(string)
+
+	// events for the same message of the same service are grouped
+	ev.AggregationKey = shorten(service+":"+e.Message, maxEventAggregationKey)
+
+	return ev
+}
+
+// postEvent sends ev to the Events API, reporting failures to OnError.
+func (d *Hook) postEvent(ev event) {
+	err := d.requestEvent(ev)
+	if err != nil {
+		d.eventFailed(err)
+	}
+}
+
+// requestEvent makes the request sending ev to the Events API.
+func (d *Hook) requestEvent(ev event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(d.client.ctx, d.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", d.opts.EventsURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	key, err := d.client.apiKey()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("DD-API-KEY", key)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := d.client.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &StatusError{StatusCode: res.StatusCode}
+	}
+
+	return nil
+}
+
+// alertType returns the alert type of events for entries at level.
+func alertType(level logrus.Level) string {
+	switch {
+	case level <= logrus.ErrorLevel:
+		return "error"
+	case level == logrus.WarnLevel:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// shorten cuts s to at most max bytes, without splitting a character.
+func shorten(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	keep := max - len(truncationSuffix)
+	for keep > 0 && !utf8.RuneStart(s[keep]) {
+		keep--
+	}
+
+	return s[:keep] + truncationSuffix
+}
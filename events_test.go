@@ -0,0 +1,138 @@
+package dogrus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// eventsIntake is an Events API, at /events, recording the titles of the
+// events. It holds the requests until release is closed.
+type eventsIntake struct {
+	*httptest.Server
+	release chan struct{}
+
+	mu     sync.Mutex
+	titles []string
+}
+
+func newEventsIntake(t *testing.T) *eventsIntake {
+	in := &eventsIntake{release: make(chan struct{})}
+	in.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			// logs are accepted right away
+			return
+		}
+
+		var ev event
+		json.NewDecoder(r.Body).Decode(&ev)
+		<-in.release
+
+		in.mu.Lock()
+		in.titles = append(in.titles, ev.Title)
+		in.mu.Unlock()
+	}))
+	t.Cleanup(in.Close)
+
+	return in
+}
+
+func (in *eventsIntake) received() []string {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return append([]string(nil), in.titles...)
+}
+
+func TestEventsPostedInBackground(t *testing.T) {
+	in := newEventsIntake(t)
+
+	d := New("key", Opts{
+		EventLevels: LevelsFrom(logrus.ErrorLevel),
+		EventsURL:   in.URL + "/events",
+		PostURL:     in.URL,
+	})
+
+	logger := logrus.New()
+	logger.AddHook(d)
+
+	start := time.Now()
+	logger.Error("disk full")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Fire waited %s for the event", elapsed)
+	}
+
+	close(in.release)
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := in.received(); len(got) != 1 || got[0] != "error: disk full" {
+		t.Fatalf("events %q, want the error one", got)
+	}
+}
+
+func TestEventsPanicPostedBeforeFireReturns(t *testing.T) {
+	in := newEventsIntake(t)
+	close(in.release)
+
+	d := New("key", Opts{
+		EventLevels: LevelsFrom(logrus.ErrorLevel),
+		EventsURL:   in.URL + "/events",
+		PostURL:     in.URL,
+	})
+	defer d.Close()
+
+	logger := logrus.New()
+	logger.AddHook(d)
+
+	func() {
+		defer func() { recover() }()
+		logger.Panic("corrupted state")
+	}()
+
+	if got := in.received(); len(got) != 1 || got[0] != "panic: corrupted state" {
+		t.Fatalf("events %q, want the panic one", got)
+	}
+}
+
+func TestEventsFull(t *testing.T) {
+	in := newEventsIntake(t)
+
+	var mu sync.Mutex
+	var full int
+	d := New("key", Opts{
+		EventLevels: LevelsFrom(logrus.ErrorLevel),
+		EventsURL:   in.URL + "/events",
+		PostURL:     in.URL,
+		OnError: func(err error, _ [][]byte) {
+			if err == ErrEventsFull {
+				mu.Lock()
+				full++
+				mu.Unlock()
+			}
+		},
+	})
+
+	logger := logrus.New()
+	logger.AddHook(d)
+
+	// one event is being posted, the others wait in the queue
+	for i := 0; i < eventQueueSize+5; i++ {
+		logger.Error("disk full")
+		time.Sleep(time.Millisecond)
+	}
+
+	close(in.release)
+	d.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if full < 4 || full > 5 {
+		t.Fatalf("%d events discarded, want 4 or 5", full)
+	}
+}
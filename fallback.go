@@ -0,0 +1,24 @@
+package dogrus
+
+// fallback writes entries that are going to be lost to Opts.Fallback, one
+// per line.
+func (c *Client) fallback(entries ...[]byte) {
+	if c.opts.Fallback == nil {
+		return
+	}
+
+	c.fallbackMu.Lock()
+	defer c.fallbackMu.Unlock()
+
+	for _, entry := range entries {
+		if len(entry) == 0 {
+			continue
+		}
+
+		// errors are ignored, there's nowhere else to put these entries
+		c.opts.Fallback.Write(entry)
+		if entry[len(entry)-1] != '\n' {
+			c.opts.Fallback.Write([]byte{'\n'})
+		}
+	}
+}
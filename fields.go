@@ -0,0 +1,53 @@
+package dogrus
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fieldSet is a set of field names, matched case-insensitively.
+type fieldSet map[string]struct{}
+
+func newFieldSet(names []string) fieldSet {
+	if len(names) == 0 {
+		return nil
+	}
+
+	set := make(fieldSet, len(names))
+	for _, name := range names {
+		set[strings.ToLower(name)] = struct{}{}
+	}
+
+	return set
+}
+
+func (s fieldSet) has(name string) bool {
+	_, ok := s[strings.ToLower(name)]
+	return ok
+}
+
+// filterFields removes from e the fields in Opts.DenyFields and, if
+// Opts.AllowFields is set, the ones missing from it. The fields the hook
+// turns into its own attributes, the error and the overrides, are only
+// removed if denied: mapError and override take care of them.
+func (d *Hook) filterFields(e *logrus.Entry) {
+	if d.denied == nil && d.allowed == nil {
+		return
+	}
+
+	for k := range e.Data {
+		if d.denied.has(k) || (d.allowed != nil && !d.allowed.has(k) && !d.mapped(k)) {
+			delete(e.Data, k)
+		}
+	}
+}
+
+// mapped tells if field k is turned into attributes of the hook.
+func (d *Hook) mapped(k string) bool {
+	if _, ok := overrides[k]; ok || k == OverrideTagsKey {
+		return true
+	}
+
+	return k == logrus.ErrorKey && !d.opts.DisableErrorAttributes
+}
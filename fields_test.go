@@ -0,0 +1,108 @@
+package dogrus
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newTestHook returns a hook that never reaches the network, closed at the
+// end of the test.
+func newTestHook(t *testing.T, opts Opts) *Hook {
+	t.Helper()
+
+	if opts.Sink == nil {
+		opts.Sink = NewMemorySink()
+	}
+
+	d := New("key", opts)
+	t.Cleanup(func() { d.Close() })
+
+	return d
+}
+
+func TestAllowFieldsKeepsHookAttributes(t *testing.T) {
+	d := newTestHook(t, Opts{
+		AllowFields: []string{"user"},
+		Service:     "checkout",
+		Tags:        []string{"env:prod"},
+	})
+
+	pc, file, line, _ := runtime.Caller(0)
+	entry := &logrus.Entry{
+		Logger: logrus.New(),
+		Time:   time.Now(),
+		Level:  logrus.ErrorLevel,
+		Data: logrus.Fields{
+			"user":              "alice",
+			"password":          "secret",
+			logrus.ErrorKey:     errors.New("boom"),
+			OverrideHostnameKey: "edge-1",
+			OverrideTagsKey:     "team:payments",
+		},
+		Caller: &runtime.Frame{
+			PC:       pc,
+			File:     file,
+			Line:     line,
+			Function: "github.com/Pitasi/dogrus.TestAllowFieldsKeepsHookAttributes",
+		},
+		Message: "payment failed",
+	}
+
+	e := d.prepare(entry, 1)
+
+	want := logrus.Fields{
+		"user":              "alice",
+		ErrorMessageKey:     "boom",
+		ErrorKindKey:        "*errors.errorString",
+		LoggerNameKey:       "github.com/Pitasi/dogrus",
+		LoggerMethodNameKey: "TestAllowFieldsKeepsHookAttributes",
+		LoggerFileKey:       file,
+		LoggerLineKey:       line,
+		ServiceKey:          "checkout",
+		HostnameKey:         "edge-1",
+		TagsKey:             "env:prod,team:payments",
+	}
+	for k, v := range want {
+		if e.Data[k] != v {
+			t.Errorf("%s is %v, want %v", k, e.Data[k], v)
+		}
+	}
+
+	for _, k := range []string{"password", logrus.ErrorKey, OverrideHostnameKey, OverrideTagsKey} {
+		if _, ok := e.Data[k]; ok {
+			t.Errorf("%s has not been removed", k)
+		}
+	}
+}
+
+func TestAllowFieldsWithErrorAllowed(t *testing.T) {
+	d := newTestHook(t, Opts{AllowFields: []string{logrus.ErrorKey}})
+
+	err := errors.New("boom")
+	e := d.prepare(logrus.New().WithError(err).WithField("other", 1), 1)
+
+	if e.Data[logrus.ErrorKey] != err || e.Data[ErrorMessageKey] != "boom" {
+		t.Errorf("error fields are %v and %v", e.Data[logrus.ErrorKey], e.Data[ErrorMessageKey])
+	}
+	if _, ok := e.Data["other"]; ok {
+		t.Error("other has not been removed")
+	}
+}
+
+func TestDenyFieldsRemovesMappedFields(t *testing.T) {
+	d := newTestHook(t, Opts{DenyFields: []string{logrus.ErrorKey, OverrideServiceKey}, Service: "checkout"})
+
+	entry := logrus.New().WithError(errors.New("boom")).WithField(OverrideServiceKey, "other")
+	e := d.prepare(entry, 1)
+
+	if _, ok := e.Data[ErrorMessageKey]; ok {
+		t.Error("denied error has been mapped")
+	}
+	if e.Data[ServiceKey] != "checkout" {
+		t.Errorf("service is %v, want checkout", e.Data[ServiceKey])
+	}
+}
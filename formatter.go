@@ -0,0 +1,171 @@
+package dogrus
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DatadogTimestampFormat is ISO 8601 with milliseconds, the precision
+// Datadog keeps for log timestamps.
+const DatadogTimestampFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// datadogAttributes are the reserved and standard attributes that Datadog
+// reads from the top level of an entry. DatadogFormatter never nests them.
+var datadogAttributes = map[string]bool{
+	ServiceKey:          true,
+	TagsKey:             true,
+	SourceKey:           true,
+	HostnameKey:         true,
+	StatusKey:           true,
+	TraceIDKey:          true,
+	SpanIDKey:           true,
+	ErrorMessageKey:     true,
+	ErrorKindKey:        true,
+	ErrorStackKey:       true,
+	LoggerNameKey:       true,
+	LoggerMethodNameKey: true,
+	LoggerFileKey:       true,
+	LoggerLineKey:       true,
+}
+
+// DatadogFormatter formats logrus entries as JSON objects laid out for
+// Datadog: the message, the timestamp and the status use the reserved
+// attributes, and the fields can be nested or flattened under FieldsKey.
+// It can be used as Opts.Formatter, or as the formatter of a logger whose
+// output is tailed by the Datadog Agent.
+type DatadogFormatter struct {
+	// FieldsKey, if set, is the attribute holding the logrus fields, such
+	// as "attributes". Reserved and standard Datadog attributes, like
+	// ServiceKey or ErrorMessageKey, are kept at the top level.
+	// By default the fields are at the top level, renamed to fields.<key>
+	// if they clash with message or timestamp.
+	FieldsKey string
+
+	// Flatten writes nested maps as dotted keys, and the fields as
+	// FieldsKey.<key> instead of an object under FieldsKey.
+	Flatten bool
+
+	// TimestampFormat is the layout of the timestamp, in UTC.
+	// By default is DatadogTimestampFormat.
+	TimestampFormat string
+
+	// Marshal encodes the entries.
+	// By default is json.Marshal.
+	Marshal MarshalFunc
+}
+
+// Format implements logrus.Formatter. Values that can't be encoded, such
+// as channels or functions, are written with fmt instead of failing the
+// whole entry.
+func (f *DatadogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(entry.Data)+4)
+
+	fields := data
+	prefix := ""
+	if f.FieldsKey != "" {
+		if f.Flatten {
+			prefix = f.FieldsKey + "."
+		} else {
+			fields = make(map[string]interface{}, len(entry.Data))
+			data[f.FieldsKey] = fields
+		}
+	}
+
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			// errors have no exported fields, they'd be encoded as {}
+			v = err.Error()
+		}
+
+		if datadogAttributes[k] {
+			data[k] = v
+			continue
+		}
+
+		if f.FieldsKey == "" && (k == "message" || k == "timestamp") {
+			// renamed like logrus.JSONFormatter does
+			k = "fields." + k
+		}
+		f.put(fields, prefix+k, v)
+	}
+
+	layout := f.TimestampFormat
+	if layout == "" {
+		layout = DatadogTimestampFormat
+	}
+
+	data["timestamp"] = entry.Time.UTC().Format(layout)
+	data["message"] = entry.Message
+
+	if _, ok := data[StatusKey]; !ok {
+		data[StatusKey] = DatadogStatuses[entry.Level]
+	}
+
+	if entry.HasCaller() {
+		pkg, method := SplitFunction(entry.Caller.Function)
+		data[LoggerNameKey] = pkg
+		data[LoggerMethodNameKey] = method
+		data[LoggerFileKey] = entry.Caller.File
+		data[LoggerLineKey] = entry.Caller.Line
+	}
+
+	marshal := f.Marshal
+	if marshal == nil {
+		marshal = json.Marshal
+	}
+
+	serialized, err := marshal(data)
+	if err != nil {
+		// find the values that can't be encoded and try again
+		sanitize(data, marshal)
+		serialized, err = marshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal fields to JSON, %w", err)
+		}
+	}
+
+	return append(serialized, '\n'), nil
+}
+
+// put sets k to v in m, flattening nested maps into dotted keys if needed.
+func (f *DatadogFormatter) put(m map[string]interface{}, k string, v interface{}) {
+	if f.Flatten {
+		switch nested := v.(type) {
+		case map[string]interface{}:
+			for nk, nv := range nested {
+				f.put(m, k+"."+nk, nv)
+			}
+			return
+		case logrus.Fields:
+			for nk, nv := range nested {
+				f.put(m, k+"."+nk, nv)
+			}
+			return
+		}
+	}
+
+	m[k] = v
+}
+
+// sanitize replaces the values of m that marshal can't encode with their
+// fmt representation. Nested maps may belong to the caller, so they are
+// copied before being changed.
+func sanitize(m map[string]interface{}, marshal MarshalFunc) {
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			copied := make(map[string]interface{}, len(nested))
+			for nk, nv := range nested {
+				copied[nk] = nv
+			}
+			sanitize(copied, marshal)
+			m[k] = copied
+			continue
+		}
+
+		if _, err := marshal(v); err != nil {
+			m[k] = fmt.Sprintf("%+v", v)
+		}
+	}
+}
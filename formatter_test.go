@@ -0,0 +1,188 @@
+package dogrus_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDatadogFormatter(t *testing.T) {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 890123456, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name      string
+		formatter dogrus.DatadogFormatter
+		level     logrus.Level
+		data      logrus.Fields
+		want      map[string]interface{}
+	}{
+		{
+			name:  "top level fields",
+			level: logrus.WarnLevel,
+			data: logrus.Fields{
+				"user":      "alice",
+				"message":   "clash",
+				"timestamp": "clash",
+				"err":       errors.New("boom"),
+			},
+			want: map[string]interface{}{
+				"timestamp":        "2026-03-04T04:06:07.890Z",
+				"message":          "hello",
+				"status":           "warning",
+				"user":             "alice",
+				"fields.message":   "clash",
+				"fields.timestamp": "clash",
+				"err":              "boom",
+			},
+		},
+		{
+			name:      "nested under FieldsKey",
+			formatter: dogrus.DatadogFormatter{FieldsKey: "attributes"},
+			data: logrus.Fields{
+				"user":                 "alice",
+				"message":              "kept",
+				dogrus.ServiceKey:      "checkout",
+				dogrus.ErrorMessageKey: "boom",
+				dogrus.StatusKey:       "notice",
+			},
+			want: map[string]interface{}{
+				"timestamp":     "2026-03-04T04:06:07.890Z",
+				"message":       "hello",
+				"status":        "notice",
+				"service":       "checkout",
+				"error.message": "boom",
+				"attributes":    map[string]interface{}{"user": "alice", "message": "kept"},
+			},
+		},
+		{
+			name:      "flattened",
+			formatter: dogrus.DatadogFormatter{Flatten: true},
+			data: logrus.Fields{
+				"http": map[string]interface{}{"method": "GET", "url": map[string]interface{}{"path": "/"}},
+				"db":   logrus.Fields{"rows": 3},
+			},
+			want: map[string]interface{}{
+				"timestamp":     "2026-03-04T04:06:07.890Z",
+				"message":       "hello",
+				"status":        "info",
+				"http.method":   "GET",
+				"http.url.path": "/",
+				"db.rows":       float64(3),
+			},
+		},
+		{
+			name:      "flattened under FieldsKey",
+			formatter: dogrus.DatadogFormatter{FieldsKey: "attributes", Flatten: true},
+			data: logrus.Fields{
+				"http":            map[string]interface{}{"method": "GET"},
+				dogrus.ServiceKey: "checkout",
+			},
+			want: map[string]interface{}{
+				"timestamp":              "2026-03-04T04:06:07.890Z",
+				"message":                "hello",
+				"status":                 "info",
+				"service":                "checkout",
+				"attributes.http.method": "GET",
+			},
+		},
+		{
+			name:      "timestamp format",
+			formatter: dogrus.DatadogFormatter{TimestampFormat: time.RFC3339Nano},
+			level:     logrus.ErrorLevel,
+			want: map[string]interface{}{
+				"timestamp": "2026-03-04T04:06:07.890123456Z",
+				"message":   "hello",
+				"status":    "error",
+			},
+		},
+		{
+			name: "values that can't be encoded",
+			data: logrus.Fields{
+				"inf":    math.Inf(1),
+				"nested": map[string]interface{}{"nan": math.NaN(), "ok": "yes"},
+			},
+			want: map[string]interface{}{
+				"timestamp": "2026-03-04T04:06:07.890Z",
+				"message":   "hello",
+				"status":    "info",
+				"inf":       "+Inf",
+				"nested":    map[string]interface{}{"nan": "NaN", "ok": "yes"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level := tt.level
+			if level == 0 {
+				level = logrus.InfoLevel
+			}
+
+			entry := &logrus.Entry{Data: tt.data, Time: at, Level: level, Message: "hello"}
+			if entry.Data == nil {
+				entry.Data = logrus.Fields{}
+			}
+
+			b, err := tt.formatter.Format(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b[len(b)-1] != '\n' {
+				t.Errorf("%q doesn't end with a newline", b)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("formatted %s, want %v", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatadogFormatterCaller(t *testing.T) {
+	entry := &logrus.Entry{
+		Logger:  &logrus.Logger{ReportCaller: true},
+		Data:    logrus.Fields{},
+		Time:    time.Now(),
+		Level:   logrus.InfoLevel,
+		Message: "hello",
+		Caller: &runtime.Frame{
+			Function: "github.com/Pitasi/dogrus/checkout.(*Cart).Add",
+			File:     "/src/checkout/cart.go",
+			Line:     42,
+		},
+	}
+
+	b, err := (&dogrus.DatadogFormatter{}).Format(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		dogrus.LoggerNameKey:       "github.com/Pitasi/dogrus/checkout",
+		dogrus.LoggerMethodNameKey: "(*Cart).Add",
+		dogrus.LoggerFileKey:       "/src/checkout/cart.go",
+		dogrus.LoggerLineKey:       float64(42),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s is %v, want %v", k, got[k], v)
+		}
+	}
+}
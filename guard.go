@@ -0,0 +1,46 @@
+package dogrus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrPanic is reported when handling an entry panics, for example in a
+// Transformer or in the Formatter. The entry is lost, but the panic doesn't
+// reach the logging call.
+var ErrPanic = errors.New("dogrus: panic while handling entry")
+
+// skipKey marks the contexts returned by Skip.
+type skipKey struct{}
+
+// Skip returns a copy of ctx marking the entries logged with it, see
+// logrus.WithContext, to be ignored by every dogrus hook.
+// It must be used when logging from callbacks of the hook, such as OnError,
+// through a logger the hook is attached to: those entries would otherwise
+// end up in the same queue they are reporting about, possibly forever.
+func Skip(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipKey{}, true)
+}
+
+// skipped tells if entry was logged with a context returned by Skip.
+func skipped(entry *logrus.Entry) bool {
+	return entry.Context != nil && entry.Context.Value(skipKey{}) != nil
+}
+
+// recoverFire turns a panic in Fire into an error reported to OnError,
+// stored in err.
+func (d *Hook) recoverFire(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	*err = fmt.Errorf("%w: %v", ErrPanic, r)
+
+	if d.opts.OnError != nil {
+		d.opts.OnError(*err, nil)
+	}
+}
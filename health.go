@@ -0,0 +1,119 @@
+package dogrus
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Health describes whether entries are being delivered, to be exposed by a
+// readiness endpoint, see HealthHandler.
+type Health struct {
+	// Healthy is false when the client is closed, offline, the circuit
+	// breaker is open or the queue is full.
+	Healthy bool
+
+	// LastFlush is the time of the last successful delivery, zero if there
+	// wasn't any.
+	LastFlush time.Time
+
+	// SinceLastFlush is the time elapsed since LastFlush, or since the
+	// client was created if nothing has been delivered yet.
+	SinceLastFlush time.Duration
+
+	// ConsecutiveFailures is the number of batches that couldn't be
+	// delivered since the last successful one.
+	ConsecutiveFailures int
+
+	// Circuit is the current state of the circuit breaker.
+	Circuit CircuitState
+
+	// QueueDepth is the number of entries currently waiting in the queue.
+	QueueDepth int
+
+	// QueueSize is how many entries the queue can hold.
+	QueueSize int
+
+	// Offline tells if the client is in the offline mode of Opts.Offline.
+	Offline bool
+
+	// Backlog is the number of entries kept while offline, or being sent
+	// after the intake became reachable again.
+	Backlog int
+
+	// Closed tells if Close has been called.
+	Closed bool
+}
+
+// Health returns the delivery status of the client.
+// It's safe to call it concurrently with the client being used.
+func (c *Client) Health() Health {
+	h := Health{
+		ConsecutiveFailures: int(atomic.LoadInt64(&c.failures)),
+		Circuit:             c.breaker.State(),
+		QueueDepth:          len(c.queue),
+		QueueSize:           cap(c.queue),
+	}
+	h.Offline, h.Backlog = c.Offline()
+
+	select {
+	case <-c.closing:
+		h.Closed = true
+	default:
+	}
+
+	since := c.created
+	if last := atomic.LoadInt64(&c.lastFlush); last != 0 {
+		h.LastFlush = time.Unix(0, last)
+		since = h.LastFlush
+	}
+	h.SinceLastFlush = c.opts.Clock.Now().Sub(since)
+
+	h.Healthy = !h.Closed && !h.Offline && h.Circuit != CircuitOpen && h.QueueDepth < h.QueueSize
+
+	return h
+}
+
+// Health returns the delivery status of the hook, combining the one of its
+// Client and of the clients of Router destinations, but not of Mirrors: it's
+// healthy only if all of them are.
+// It's safe to call it concurrently with the hook being used.
+func (d *Hook) Health() Health {
+	h := d.client.Health()
+
+	for _, c := range d.routed() {
+		o := c.Health()
+
+		h.Healthy = h.Healthy && o.Healthy
+		h.QueueDepth += o.QueueDepth
+		h.QueueSize += o.QueueSize
+		h.Offline = h.Offline || o.Offline
+		h.Backlog += o.Backlog
+
+		if o.ConsecutiveFailures > h.ConsecutiveFailures {
+			h.ConsecutiveFailures = o.ConsecutiveFailures
+		}
+
+		if o.SinceLastFlush > h.SinceLastFlush {
+			h.LastFlush, h.SinceLastFlush = o.LastFlush, o.SinceLastFlush
+		}
+	}
+
+	return h
+}
+
+// HealthHandler returns an http.Handler serving the Health of c, a Client
+// or a Hook, as JSON, with status 503 when it's not healthy.
+func HealthHandler(c interface{ Health() Health }) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := c.Health()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !h.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(h)
+	})
+}
@@ -0,0 +1,31 @@
+package dogrus
+
+import "github.com/sirupsen/logrus"
+
+// LevelsFrom returns the levels at least as severe as min, to be used as
+// Opts.Levels. For example LevelsFrom(logrus.InfoLevel) excludes debug and
+// trace entries.
+func LevelsFrom(min logrus.Level) []logrus.Level {
+	levels := make([]logrus.Level, 0, len(logrus.AllLevels))
+	for _, level := range logrus.AllLevels {
+		if level <= min {
+			levels = append(levels, level)
+		}
+	}
+
+	return levels
+}
+
+// levelSet returns levels as a set, nil if empty.
+func levelSet(levels []logrus.Level) map[logrus.Level]bool {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	set := make(map[logrus.Level]bool, len(levels))
+	for _, level := range levels {
+		set[level] = true
+	}
+
+	return set
+}
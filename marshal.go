@@ -0,0 +1,55 @@
+package dogrus
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MarshalFunc encodes v into JSON, like json.Marshal. Faster drop-in
+// implementations, such as jsoniter.ConfigCompatibleWithStandardLibrary.Marshal,
+// can be used through Opts.Marshal.
+type MarshalFunc func(v interface{}) ([]byte, error)
+
+// marshalFormatter formats entries like the default Formatter, with the
+// Datadog standard keys, encoding them with a MarshalFunc.
+type marshalFormatter struct {
+	marshal MarshalFunc
+}
+
+// Format implements logrus.Formatter.
+func (f marshalFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(map[string]interface{}, len(entry.Data)+5)
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			// errors have no exported fields, they'd be encoded as {}
+			v = err.Error()
+		}
+		data[k] = v
+	}
+
+	// fields clashing with the standard keys are renamed like
+	// logrus.JSONFormatter does
+	clashing := []string{"timestamp", "message", "level"}
+	if entry.HasCaller() {
+		clashing = append(clashing, logrus.FieldKeyFunc, logrus.FieldKeyFile)
+	}
+	for _, k := range clashing {
+		if v, ok := data[k]; ok {
+			data["fields."+k] = v
+			delete(data, k)
+		}
+	}
+
+	data["timestamp"] = entry.Time.Format(time.RFC3339)
+	data["message"] = entry.Message
+	data["level"] = entry.Level.String()
+
+	if entry.HasCaller() {
+		data[logrus.FieldKeyFunc] = entry.Caller.Function
+		data[logrus.FieldKeyFile] = entry.Caller.File + ":" + strconv.Itoa(entry.Caller.Line)
+	}
+
+	return f.marshal(data)
+}
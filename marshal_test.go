@@ -0,0 +1,87 @@
+package dogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// compactMarshal is a MarshalFunc not escaping HTML, standing in for the
+// faster drop-in encoders.
+func compactMarshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(v)
+	return buf.Bytes(), err
+}
+
+func marshalEntry() *logrus.Entry {
+	return logrus.New().WithFields(logrus.Fields{
+		"user":          "alice",
+		"order":         12345,
+		"message":       "clashing",
+		logrus.ErrorKey: errors.New("boom"),
+	})
+}
+
+func TestMarshalFormatterMatchesDefault(t *testing.T) {
+	entry := marshalEntry()
+	entry.Message = "order placed"
+
+	def := withDefaults(Opts{}).Formatter
+	custom := withDefaults(Opts{Marshal: json.Marshal}).Formatter
+
+	var want, got map[string]interface{}
+	for _, f := range []struct {
+		formatter logrus.Formatter
+		out       *map[string]interface{}
+	}{{def, &want}, {custom, &got}} {
+		b, err := f.formatter.Format(entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(b, f.out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s is %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+// BenchmarkMarshal compares the default Formatter with the one using
+// Opts.Marshal, with encoding/json and with a pluggable encoder.
+func BenchmarkMarshal(b *testing.B) {
+	entry := marshalEntry()
+	entry.Message = "order placed"
+
+	formatters := []struct {
+		name      string
+		formatter logrus.Formatter
+	}{
+		{"default", withDefaults(Opts{}).Formatter},
+		{"encoding/json", withDefaults(Opts{Marshal: json.Marshal}).Formatter},
+		{"pluggable", withDefaults(Opts{Marshal: compactMarshal}).Formatter},
+	}
+
+	for _, f := range formatters {
+		b.Run(f.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := f.formatter.Format(entry); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
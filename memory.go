@@ -0,0 +1,89 @@
+package dogrus
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// MemorySink is a Sink keeping the entries in memory, so nothing leaves the
+// process. It's meant for unit tests and for dry runs checking what would
+// be sent, such as the fields added by enrichment, without spending Datadog
+// quota:
+//
+//	sink := dogrus.NewMemorySink()
+//	hook := dogrus.New("", dogrus.Opts{Sink: sink})
+//
+// Entries go through the same queue as with any Sink, so call Flush before
+// inspecting them.
+type MemorySink struct {
+	mu      sync.Mutex
+	entries [][]byte
+	max     int
+}
+
+// NewMemorySink returns an empty MemorySink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+// NewBoundedMemorySink returns an empty MemorySink retaining at most max
+// entries, discarding the oldest ones, for long running dry runs.
+func NewBoundedMemorySink(max int) *MemorySink {
+	return &MemorySink{max: max}
+}
+
+// Send implements Sink.
+func (s *MemorySink) Send(_ context.Context, batch [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range batch {
+		s.entries = append(s.entries, append([]byte(nil), entry...))
+	}
+
+	if s.max > 0 && len(s.entries) > s.max {
+		s.entries = append(s.entries[:0], s.entries[len(s.entries)-s.max:]...)
+	}
+
+	return nil
+}
+
+// Entries returns the entries received so far, in order.
+func (s *MemorySink) Entries() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([][]byte(nil), s.entries...)
+}
+
+// Decoded returns the entries received so far decoded from JSON.
+func (s *MemorySink) Decoded() ([]map[string]interface{}, error) {
+	entries := s.Entries()
+
+	decoded := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		err := json.Unmarshal(entry, &decoded[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return decoded, nil
+}
+
+// Len returns the number of entries received so far.
+func (s *MemorySink) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.entries)
+}
+
+// Reset discards the entries received so far.
+func (s *MemorySink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = nil
+}
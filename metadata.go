@@ -0,0 +1,93 @@
+package dogrus
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Datadog reserved attributes set by the hook.
+const (
+	ServiceKey  = "service"
+	TagsKey     = "ddtags"
+	SourceKey   = "ddsource"
+	HostnameKey = "hostname"
+)
+
+// Datadog attributes correlating logs with traces, set by the tracing
+// integrations in the otel and ddtrace subpackages.
+const (
+	TraceIDKey = "dd.trace_id"
+	SpanIDKey  = "dd.span_id"
+)
+
+// ddtags returns the tags attached to every entry, in Datadog ddtags format.
+func ddtags(opts Opts) string {
+	tags := make([]string, 0, len(opts.Tags)+2)
+	tags = append(tags, opts.Tags...)
+
+	if opts.Env != "" {
+		tags = append(tags, "env:"+opts.Env)
+	}
+
+	if opts.Version != "" {
+		tags = append(tags, "version:"+opts.Version)
+	}
+
+	return strings.Join(tags, ",")
+}
+
+// metadata returns the attributes added to every entry, given the ddtags
+// built from opts.
+func metadata(opts Opts, tags string) map[string]string {
+	m := make(map[string]string, 3)
+
+	if opts.Service != "" {
+		m[ServiceKey] = opts.Service
+	}
+
+	if tags != "" {
+		m[TagsKey] = tags
+	}
+
+	if opts.Hostname != "" {
+		m[HostnameKey] = opts.Hostname
+	}
+
+	if opts.ProcessAttributes {
+		processAttributes(m, opts.Version)
+	}
+
+	return m
+}
+
+// enrich adds the hook metadata and the Datadog status to e, and the sample
+// rate that kept it when less than 1. Fields already present in the entry
+// are left untouched.
+func (d *Hook) enrich(e *logrus.Entry, sampleRate float64) {
+	if sampleRate < 1 {
+		e.Data[SampleRateKey] = sampleRate
+	}
+
+	for k, v := range d.client.metadata {
+		if _, ok := e.Data[k]; !ok {
+			e.Data[k] = v
+		}
+	}
+
+	d.addStatus(e)
+	d.addRuntimeStats(e)
+}
+
+// Metadata returns the attributes configured by Opts to be added to every
+// entry, such as ServiceKey, TagsKey and HostnameKey. Hook adds them to
+// logrus entries, adapters of other logging libraries can use it to add the
+// same attributes to their records.
+func (c *Client) Metadata() map[string]string {
+	m := make(map[string]string, len(c.metadata))
+	for k, v := range c.metadata {
+		m[k] = v
+	}
+
+	return m
+}
@@ -0,0 +1,39 @@
+package dogrus
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestHostnameOverride(t *testing.T) {
+	kernel, err := os.Hostname()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name string
+		opt  string
+		env  string
+		want string
+	}{
+		{"Opts.Hostname", "from-opts", "from-env", "from-opts"},
+		{"DD_HOSTNAME", "", "from-env", "from-env"},
+		{"os.Hostname", "", "", kernel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DD_HOSTNAME", tt.env)
+
+			d := newTestHook(t, Opts{Hostname: tt.opt})
+			e := d.prepare(logrus.NewEntry(logrus.New()), 1)
+
+			if got := e.Data[HostnameKey]; got != tt.want {
+				t.Errorf("hostname is %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package dogrus
+
+// Metrics receives measurements about the hook activity as they happen, so
+// they can be exported to a monitoring system.
+// Methods are called from the application goroutines and from the sender
+// goroutine, so implementations must be safe for concurrent use.
+// See the metrics subpackage for a Prometheus implementation.
+type Metrics interface {
+	// QueueDepth is called with the number of queued entries every time it
+	// changes.
+	QueueDepth(depth int)
+
+	// Flushed is called after every successful delivery.
+	Flushed(stats FlushStats)
+
+	// Failed is called every time a batch can't be delivered.
+	Failed(err error)
+
+	// Dropped is called when entries are discarded because the queue is
+	// full, or because they are larger than MaxEntryBytes.
+	Dropped(n int)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) QueueDepth(int)     {}
+func (nopMetrics) Flushed(FlushStats) {}
+func (nopMetrics) Failed(error)       {}
+func (nopMetrics) Dropped(int)        {}
@@ -0,0 +1,103 @@
+// Package metrics exports dogrus hook activity as Prometheus metrics.
+//
+// It lives in its own package so that dogrus itself doesn't depend on the
+// Prometheus client:
+//
+//	m, err := metrics.New(prometheus.DefaultRegisterer)
+//	if err != nil {
+//		// ...
+//	}
+//	hook := dogrus.New(apiKey, dogrus.Opts{Metrics: m})
+package metrics
+
+import (
+	"github.com/Pitasi/dogrus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus implements dogrus.Metrics using Prometheus collectors.
+type Prometheus struct {
+	queueDepth    prometheus.Gauge
+	flushDuration prometheus.Histogram
+	bytesSent     prometheus.Counter
+	entriesSent   prometheus.Counter
+	errors        prometheus.Counter
+	drops         prometheus.Counter
+}
+
+// New creates the collectors and registers them in reg.
+func New(reg prometheus.Registerer) (*Prometheus, error) {
+	p := &Prometheus{
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dogrus",
+			Name:      "queue_depth",
+			Help:      "Number of log entries waiting to be sent.",
+		}),
+		flushDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "dogrus",
+			Name:      "flush_duration_seconds",
+			Help:      "Duration of successful requests to Datadog.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		bytesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "dogrus",
+			Name:      "sent_bytes_total",
+			Help:      "Bytes of request bodies delivered to Datadog.",
+		}),
+		entriesSent: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "dogrus",
+			Name:      "sent_entries_total",
+			Help:      "Log entries delivered to Datadog.",
+		}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "dogrus",
+			Name:      "errors_total",
+			Help:      "Batches that couldn't be delivered to Datadog.",
+		}),
+		drops: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "dogrus",
+			Name:      "dropped_entries_total",
+			Help:      "Log entries discarded because the queue was full or they were too large.",
+		}),
+	}
+
+	collectors := []prometheus.Collector{
+		p.queueDepth,
+		p.flushDuration,
+		p.bytesSent,
+		p.entriesSent,
+		p.errors,
+		p.drops,
+	}
+
+	for _, c := range collectors {
+		err := reg.Register(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// QueueDepth implements dogrus.Metrics.
+func (p *Prometheus) QueueDepth(depth int) {
+	p.queueDepth.Set(float64(depth))
+}
+
+// Flushed implements dogrus.Metrics.
+func (p *Prometheus) Flushed(stats dogrus.FlushStats) {
+	p.flushDuration.Observe(stats.Latency.Seconds())
+	p.bytesSent.Add(float64(stats.Bytes))
+	p.entriesSent.Add(float64(stats.Entries))
+}
+
+// Failed implements dogrus.Metrics.
+func (p *Prometheus) Failed(err error) {
+	p.errors.Inc()
+}
+
+// Dropped implements dogrus.Metrics.
+func (p *Prometheus) Dropped(n int) {
+	p.drops.Add(float64(n))
+}
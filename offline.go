@@ -0,0 +1,316 @@
+package dogrus
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrOffline is returned by Flush and Close while the client is offline, and
+// reported for the entries discarded because the backlog is full.
+var ErrOffline = errors.New("dogrus: intake is unreachable")
+
+// Offline configures the offline mode, for devices losing connectivity
+// regularly: after repeated network failures the client stops sending and
+// keeps the batches in a backlog, probing the intake until it's reachable
+// again and then sending the backlog at a controlled pace.
+// With a Spool, the entries also stay on disk until they are delivered, up
+// to its own size limit.
+type Offline struct {
+	// Threshold is how many network failures in a row, such as DNS errors
+	// or refused connections, switch the client to offline mode.
+	// By default is 3.
+	Threshold int
+
+	// ProbeInterval is how often the intake is probed while offline.
+	// By default is 30 seconds.
+	ProbeInterval time.Duration
+
+	// MaxBytes caps the size of the entries kept in memory while offline.
+	// When the limit is reached the oldest ones are discarded, reporting
+	// ErrOffline to OnError.
+	// By default is 8 MiB.
+	MaxBytes int
+
+	// DrainInterval is the pause between the batches of the backlog once
+	// the intake is reachable again, so the backlog doesn't compete with
+	// the application for the bandwidth that just came back.
+	// By default is 100 milliseconds.
+	DrainInterval time.Duration
+
+	// OnOffline is called with the last network error when the client
+	// switches to offline mode.
+	OnOffline func(err error)
+
+	// OnOnline is called when the backlog has been sent and the client is
+	// back to normal.
+	OnOnline func()
+}
+
+// withDefaults returns o with the default values filled in.
+func (o Offline) withDefaults() Offline {
+	if o.Threshold == 0 {
+		o.Threshold = 3
+	}
+
+	if o.ProbeInterval == 0 {
+		o.ProbeInterval = 30 * time.Second
+	}
+
+	if o.MaxBytes == 0 {
+		o.MaxBytes = 8 * 1024 * 1024
+	}
+
+	if o.DrainInterval == 0 {
+		o.DrainInterval = 100 * time.Millisecond
+	}
+
+	return o
+}
+
+// backlog holds the batches not sent while the client is offline.
+type backlog struct {
+	mu      sync.Mutex
+	active  bool
+	records []record
+	bytes   int
+	sending int // records at the head being sent by resume
+	cause   error
+	prober  sync.WaitGroup
+}
+
+// holding queues batch in the backlog if the client is offline, telling if
+// it did.
+func (c *Client) holding(batch []record) bool {
+	b := c.backlog
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	if !b.active {
+		b.mu.Unlock()
+		return false
+	}
+	discarded := c.hold(batch)
+	b.mu.Unlock()
+
+	if len(discarded) > 0 {
+		c.fail(ErrOffline, discarded)
+	}
+
+	return true
+}
+
+// goOffline switches the client to offline mode, queueing batch in the
+// backlog and starting the prober if it's not running yet.
+func (c *Client) goOffline(batch []record) {
+	b := c.backlog
+
+	b.mu.Lock()
+	err := b.cause
+	started := !b.active
+	if started {
+		b.active = true
+		b.prober.Add(1)
+		go c.probe()
+	}
+	discarded := c.hold(batch)
+	b.mu.Unlock()
+
+	if len(discarded) > 0 {
+		c.fail(ErrOffline, discarded)
+	}
+
+	if started {
+		c.debugf("offline after %d network failures: %v", atomic.LoadInt64(&c.unreachable), err)
+		if c.opts.Offline.OnOffline != nil {
+			c.opts.Offline.OnOffline(err)
+		}
+	}
+}
+
+// hold appends batch to the backlog, discarding and returning the oldest
+// records beyond Offline.MaxBytes, except the ones being sent.
+// The caller must hold the backlog lock.
+func (c *Client) hold(batch []record) []record {
+	b := c.backlog
+
+	for _, rec := range batch {
+		b.records = append(b.records, rec)
+		b.bytes += len(rec.data)
+	}
+
+	end := b.sending
+	for b.bytes > c.opts.Offline.MaxBytes && end < len(b.records) {
+		b.bytes -= len(b.records[end].data)
+		end++
+	}
+
+	if end == b.sending {
+		return nil
+	}
+
+	discarded := append([]record(nil), b.records[b.sending:end]...)
+	b.records = append(b.records[:b.sending], b.records[end:]...)
+
+	return discarded
+}
+
+// disconnected records the outcome of a request that failed with err,
+// telling if the client must switch to offline mode.
+func (c *Client) disconnected(err error) bool {
+	if c.backlog == nil || !networkError(err) {
+		atomic.StoreInt64(&c.unreachable, 0)
+		return false
+	}
+
+	if atomic.AddInt64(&c.unreachable, 1) < int64(c.opts.Offline.Threshold) {
+		return false
+	}
+
+	c.backlog.mu.Lock()
+	c.backlog.cause = err
+	c.backlog.mu.Unlock()
+
+	return true
+}
+
+// networkError tells if err means the intake couldn't be reached at all,
+// rather than it rejecting the request.
+func networkError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ENETUNREACH) ||
+		errors.Is(err, syscall.EHOSTUNREACH) ||
+		errors.Is(err, syscall.ENETDOWN)
+}
+
+// probe is the loop of the prober while the client is offline: it checks
+// every ProbeInterval if the intake is reachable, then sends the backlog.
+func (c *Client) probe() {
+	defer c.backlog.prober.Done()
+
+	for {
+		if !sleep(c.opts.Clock, c.opts.Offline.ProbeInterval, c.ctx.Done()) {
+			return
+		}
+
+		if err := c.connect(); err != nil {
+			c.debugf("intake still unreachable: %v", err)
+			continue
+		}
+
+		c.debugf("intake reachable, sending the backlog")
+		if c.resume() {
+			return
+		}
+	}
+}
+
+// resume sends the backlog one batch at a time, pausing DrainInterval
+// between them. It tells if the client is back online, false if it went
+// offline again or it's closing.
+func (c *Client) resume() bool {
+	b := c.backlog
+
+	for {
+		b.mu.Lock()
+		if len(b.records) == 0 {
+			b.active = false
+			b.records = nil
+			b.mu.Unlock()
+
+			c.debugf("online again")
+			if c.opts.Offline.OnOnline != nil {
+				c.opts.Offline.OnOnline()
+			}
+			return true
+		}
+
+		n := c.maxBatchSize()
+		if n > len(b.records) {
+			n = len(b.records)
+		}
+		batch := append([]record(nil), b.records[:n]...)
+		b.sending = n
+		b.mu.Unlock()
+
+		for len(batch) > 0 {
+			n := c.chunkLen(batch)
+			if err := c.deliver(batch[:n]); err == ErrOffline {
+				b.mu.Lock()
+				b.sending = 0
+				b.mu.Unlock()
+				return false
+			}
+			batch = batch[n:]
+			c.unhold(n)
+		}
+
+		if !sleep(c.opts.Clock, c.opts.Offline.DrainInterval, c.ctx.Done()) {
+			return false
+		}
+	}
+}
+
+// unhold removes the n oldest records from the backlog, once they have been
+// handled.
+func (c *Client) unhold(n int) {
+	b := c.backlog
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, rec := range b.records[:n] {
+		b.bytes -= len(rec.data)
+	}
+	b.records = b.records[n:]
+	b.sending -= n
+}
+
+// closeBacklog waits for the prober to stop, then reports the entries left
+// in the backlog as not delivered.
+func (c *Client) closeBacklog() {
+	b := c.backlog
+	if b == nil {
+		return
+	}
+
+	b.prober.Wait()
+
+	b.mu.Lock()
+	records := b.records
+	b.records, b.bytes = nil, 0
+	b.mu.Unlock()
+
+	if len(records) > 0 {
+		c.fail(ErrOffline, records)
+	}
+}
+
+// Offline tells if the client is in offline mode, and how many entries are
+// waiting in the backlog.
+func (c *Client) Offline() (bool, int) {
+	b := c.backlog
+	if b == nil {
+		return false, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.active, len(b.records)
+}
@@ -0,0 +1,104 @@
+package dogrus_test
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+)
+
+func TestOfflineMaxBytes(t *testing.T) {
+	var mu sync.Mutex
+	var discarded []string
+
+	intake := dogrustest.NewIntake(t, "key")
+	c := dogrus.NewClient("key", dogrus.Opts{
+		PostURL:      intake.URL,
+		FlushPeriod:  time.Hour,
+		MaxBatchSize: 1,
+		HTTPClient:   &http.Client{Transport: &unreachable{down: 1}},
+		OnError: func(err error, batch [][]byte) {
+			if err != dogrus.ErrOffline {
+				t.Errorf("reported %v, want ErrOffline", err)
+			}
+			mu.Lock()
+			for _, e := range batch {
+				discarded = append(discarded, string(e))
+			}
+			mu.Unlock()
+		},
+		Offline: &dogrus.Offline{
+			Threshold:     1,
+			ProbeInterval: time.Hour,
+			MaxBytes:      2 * len(`{"message":"1"}`),
+		},
+	})
+
+	c.Enqueue([]byte(`{"message":"1"}`))
+	c.Enqueue([]byte(`{"message":"2"}`))
+	waitFor(t, "the entries to be held", func() bool {
+		h := c.Health()
+		return h.Offline && h.Backlog == 2
+	})
+
+	// the oldest entry makes room for the new one
+	c.Enqueue([]byte(`{"message":"3"}`))
+	waitFor(t, "the first entry to be discarded", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(discarded) == 1
+	})
+	mu.Lock()
+	first := discarded[0]
+	mu.Unlock()
+	if first != `{"message":"1"}` {
+		t.Fatalf("discarded %s, want the first entry", first)
+	}
+	if h := c.Health(); h.Backlog != 2 {
+		t.Fatalf("Backlog %d, want 2", h.Backlog)
+	}
+
+	if h := c.Health(); h.Healthy {
+		t.Error("healthy while offline")
+	}
+	if err := c.Flush(); err != dogrus.ErrOffline {
+		t.Errorf("Flush returned %v, want ErrOffline", err)
+	}
+
+	// the backlog is reported as not delivered
+	c.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(discarded, " "); got != `{"message":"1"} {"message":"2"} {"message":"3"}` {
+		t.Fatalf("discarded %s, want every entry", got)
+	}
+	if n := len(intake.Entries()); n != 0 {
+		t.Fatalf("the intake received %d entries", n)
+	}
+}
+
+func TestOfflineOnlyOnNetworkErrors(t *testing.T) {
+	intake := dogrustest.NewIntake(t, "key")
+	c := dogrus.NewClient("key", dogrus.Opts{
+		PostURL:      intake.URL,
+		FlushPeriod:  time.Hour,
+		MaxBatchSize: 1,
+		OnError:      func(error, [][]byte) {},
+		Offline:      &dogrus.Offline{Threshold: 1},
+	})
+	defer c.Close()
+
+	// the intake is reachable, it's rejecting the requests
+	intake.Fail(http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	c.Enqueue([]byte(`{"message":"hello"}`))
+	waitFor(t, "the batch to fail", func() bool { return c.Stats().FailedFlushes == 1 })
+
+	if h := c.Health(); h.Offline || h.Backlog != 0 {
+		t.Fatalf("Offline %v Backlog %d after a 503, want false and 0", h.Offline, h.Backlog)
+	}
+}
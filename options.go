@@ -0,0 +1,513 @@
+package dogrus
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Option configures a Hook created with NewWithOptions.
+type Option func(*Opts)
+
+// NewWithOptions creates a new Hook using the API key provided, configured
+// by the given options. Everything not configured gets the same default
+// value as in New.
+func NewWithOptions(apiKey string, options ...Option) *Hook {
+	var opts Opts
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return New(apiKey, opts)
+}
+
+// WithFlushPeriod sets Opts.FlushPeriod.
+func WithFlushPeriod(period time.Duration) Option {
+	return func(o *Opts) {
+		o.FlushPeriod = period
+	}
+}
+
+// WithFlushJitter sets Opts.FlushJitter.
+func WithFlushJitter(jitter float64) Option {
+	return func(o *Opts) {
+		o.FlushJitter = jitter
+	}
+}
+
+// WithMaxBatchSize sets Opts.MaxBatchSize.
+func WithMaxBatchSize(size int) Option {
+	return func(o *Opts) {
+		o.MaxBatchSize = size
+	}
+}
+
+// WithMaxBatchBytes sets Opts.MaxBatchBytes.
+func WithMaxBatchBytes(size int) Option {
+	return func(o *Opts) {
+		o.MaxBatchBytes = size
+	}
+}
+
+// WithMaxEntryBytes sets Opts.MaxEntryBytes and Opts.OversizePolicy.
+func WithMaxEntryBytes(size int, policy OversizePolicy) Option {
+	return func(o *Opts) {
+		o.MaxEntryBytes = size
+		o.OversizePolicy = policy
+	}
+}
+
+// WithQueue sets Opts.QueueSize and Opts.OverflowPolicy.
+func WithQueue(size int, policy OverflowPolicy) Option {
+	return func(o *Opts) {
+		o.QueueSize = size
+		o.OverflowPolicy = policy
+	}
+}
+
+// WithMaxQueuedBytes sets Opts.MaxQueuedBytes.
+func WithMaxQueuedBytes(size int) Option {
+	return func(o *Opts) {
+		o.MaxQueuedBytes = size
+	}
+}
+
+// WithWatermarks sets Opts.Watermarks.
+func WithWatermarks(w Watermarks) Option {
+	return func(o *Opts) {
+		o.Watermarks = &w
+	}
+}
+
+// WithSite sets Opts.Site.
+func WithSite(site string) Option {
+	return func(o *Opts) {
+		o.Site = site
+	}
+}
+
+// WithService sets Opts.Service.
+func WithService(service string) Option {
+	return func(o *Opts) {
+		o.Service = service
+	}
+}
+
+// WithHostname sets Opts.Hostname.
+func WithHostname(hostname string) Option {
+	return func(o *Opts) {
+		o.Hostname = hostname
+	}
+}
+
+// WithProcessAttributes sets Opts.ProcessAttributes and Opts.RuntimeStats.
+func WithProcessAttributes(runtimeStats bool) Option {
+	return func(o *Opts) {
+		o.ProcessAttributes = true
+		o.RuntimeStats = runtimeStats
+	}
+}
+
+// WithEnv sets Opts.Env.
+func WithEnv(env string) Option {
+	return func(o *Opts) {
+		o.Env = env
+	}
+}
+
+// WithVersion sets Opts.Version.
+func WithVersion(version string) Option {
+	return func(o *Opts) {
+		o.Version = version
+	}
+}
+
+// WithTags adds tags to Opts.Tags.
+func WithTags(tags ...string) Option {
+	return func(o *Opts) {
+		o.Tags = append(o.Tags, tags...)
+	}
+}
+
+// WithSink sets Opts.Sink.
+func WithSink(sink Sink) Option {
+	return func(o *Opts) {
+		o.Sink = sink
+	}
+}
+
+// WithPostURL sets Opts.PostURL.
+func WithPostURL(postURL string) Option {
+	return func(o *Opts) {
+		o.PostURL = postURL
+	}
+}
+
+// WithTCP sets Opts.Protocol to ProtocolTCP and Opts.TCPAddr to addr, which
+// can be empty to use the intake of Opts.Site.
+func WithTCP(addr string) Option {
+	return func(o *Opts) {
+		o.Protocol = ProtocolTCP
+		o.TCPAddr = addr
+	}
+}
+
+// WithAgent sets Opts.Protocol to ProtocolAgent and Opts.TCPAddr to addr,
+// which can be empty to use the default agent address.
+func WithAgent(addr string) Option {
+	return func(o *Opts) {
+		o.Protocol = ProtocolAgent
+		o.TCPAddr = addr
+	}
+}
+
+// WithTLSConfig sets Opts.TLSConfig.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *Opts) {
+		o.TLSConfig = cfg
+	}
+}
+
+// WithUnixSocket sets Opts.UnixSocketPath.
+func WithUnixSocket(path string) Option {
+	return func(o *Opts) {
+		o.UnixSocketPath = path
+	}
+}
+
+// WithLevels sets Opts.Levels.
+func WithLevels(levels ...logrus.Level) Option {
+	return func(o *Opts) {
+		o.Levels = levels
+	}
+}
+
+// WithMinLevel sets Opts.Levels to the levels at least as severe as min.
+func WithMinLevel(min logrus.Level) Option {
+	return func(o *Opts) {
+		o.Levels = LevelsFrom(min)
+	}
+}
+
+// WithPriorityLevel sets Opts.PriorityLevels to the levels at least as
+// severe as min.
+func WithPriorityLevel(min logrus.Level) Option {
+	return func(o *Opts) {
+		o.PriorityLevels = LevelsFrom(min)
+	}
+}
+
+// WithEvents sets Opts.EventLevels.
+func WithEvents(levels ...logrus.Level) Option {
+	return func(o *Opts) {
+		o.EventLevels = levels
+	}
+}
+
+// WithSampleRates sets Opts.SampleRates.
+func WithSampleRates(rates map[logrus.Level]float64) Option {
+	return func(o *Opts) {
+		o.SampleRates = rates
+	}
+}
+
+// WithAdaptiveSampling sets Opts.AdaptiveSampling.
+func WithAdaptiveSampling(cfg AdaptiveSampling) Option {
+	return func(o *Opts) {
+		o.AdaptiveSampling = &cfg
+	}
+}
+
+// WithDedupWindow sets Opts.DedupWindow.
+func WithDedupWindow(window time.Duration) Option {
+	return func(o *Opts) {
+		o.DedupWindow = window
+	}
+}
+
+// WithTransformers adds transformers to Opts.Transformers.
+func WithTransformers(transformers ...Transformer) Option {
+	return func(o *Opts) {
+		o.Transformers = append(o.Transformers, transformers...)
+	}
+}
+
+// WithStackExtractor sets Opts.StackExtractor.
+func WithStackExtractor(extractor StackExtractor) Option {
+	return func(o *Opts) {
+		o.StackExtractor = extractor
+	}
+}
+
+// WithCaptureStack sets Opts.CaptureStack.
+func WithCaptureStack(enabled bool) Option {
+	return func(o *Opts) {
+		o.CaptureStack = enabled
+	}
+}
+
+// WithDenyFields adds names to Opts.DenyFields.
+func WithDenyFields(names ...string) Option {
+	return func(o *Opts) {
+		o.DenyFields = append(o.DenyFields, names...)
+	}
+}
+
+// WithAllowFields adds names to Opts.AllowFields.
+func WithAllowFields(names ...string) Option {
+	return func(o *Opts) {
+		o.AllowFields = append(o.AllowFields, names...)
+	}
+}
+
+// WithScrubRules adds rules to Opts.ScrubRules.
+func WithScrubRules(rules ...ScrubRule) Option {
+	return func(o *Opts) {
+		o.ScrubRules = append(o.ScrubRules, rules...)
+	}
+}
+
+// WithFormatter sets Opts.Formatter.
+func WithFormatter(formatter logrus.Formatter) Option {
+	return func(o *Opts) {
+		o.Formatter = formatter
+	}
+}
+
+// WithMarshal sets Opts.Marshal.
+func WithMarshal(marshal MarshalFunc) Option {
+	return func(o *Opts) {
+		o.Marshal = marshal
+	}
+}
+
+// WithStatuses sets Opts.Statuses. A nil map sets Opts.DisableStatus.
+func WithStatuses(statuses map[logrus.Level]string) Option {
+	return func(o *Opts) {
+		o.Statuses = statuses
+		o.DisableStatus = statuses == nil
+	}
+}
+
+// WithHTTPClient sets Opts.HTTPClient.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *Opts) {
+		o.HTTPClient = client
+	}
+}
+
+// WithIdleConns sets Opts.MaxIdleConnsPerHost and Opts.IdleConnTimeout.
+func WithIdleConns(maxPerHost int, timeout time.Duration) Option {
+	return func(o *Opts) {
+		o.MaxIdleConnsPerHost = maxPerHost
+		o.IdleConnTimeout = timeout
+	}
+}
+
+// WithWarmUp sets Opts.WarmUp.
+func WithWarmUp(enabled bool) Option {
+	return func(o *Opts) {
+		o.WarmUp = enabled
+	}
+}
+
+// WithHTTP2 sets Opts.DisableHTTP2 to the opposite of enabled.
+func WithHTTP2(enabled bool) Option {
+	return func(o *Opts) {
+		o.DisableHTTP2 = !enabled
+	}
+}
+
+// WithProxyURL sets Opts.ProxyURL.
+func WithProxyURL(proxyURL *url.URL) Option {
+	return func(o *Opts) {
+		o.ProxyURL = proxyURL
+	}
+}
+
+// WithAPIKeyFunc sets Opts.APIKeyFunc.
+func WithAPIKeyFunc(f func() (string, error)) Option {
+	return func(o *Opts) {
+		o.APIKeyFunc = f
+	}
+}
+
+// WithAPIKeyInPath sets Opts.APIKeyInPath.
+func WithAPIKeyInPath(enabled bool) Option {
+	return func(o *Opts) {
+		o.APIKeyInPath = enabled
+	}
+}
+
+// WithHeader adds a header to Opts.Headers.
+func WithHeader(key, value string) Option {
+	return func(o *Opts) {
+		if o.Headers == nil {
+			o.Headers = make(map[string]string)
+		}
+		o.Headers[key] = value
+	}
+}
+
+// WithBeforeSend adds steps to Opts.BeforeSend.
+func WithBeforeSend(steps ...BeforeSend) Option {
+	return func(o *Opts) {
+		o.BeforeSend = append(o.BeforeSend, steps...)
+	}
+}
+
+// WithRequestTimeout sets Opts.RequestTimeout.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(o *Opts) {
+		o.RequestTimeout = timeout
+	}
+}
+
+// WithContext sets Opts.Context.
+func WithContext(ctx context.Context) Option {
+	return func(o *Opts) {
+		o.Context = ctx
+	}
+}
+
+// WithCompression sets Opts.Compress.
+func WithCompression(enabled bool) Option {
+	return func(o *Opts) {
+		o.Compress = enabled
+	}
+}
+
+// WithBodyFormat sets Opts.BodyFormat.
+func WithBodyFormat(format BodyFormat) Option {
+	return func(o *Opts) {
+		o.BodyFormat = format
+	}
+}
+
+// WithRetries sets Opts.MaxRetries and Opts.RetryBackoff.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(o *Opts) {
+		o.MaxRetries = maxRetries
+		o.RetryBackoff = backoff
+	}
+}
+
+// WithRetryBudget sets Opts.RetryFor and Opts.MaxRetryBytes.
+func WithRetryBudget(retryFor time.Duration, maxBytes int) Option {
+	return func(o *Opts) {
+		o.RetryFor = retryFor
+		o.MaxRetryBytes = maxBytes
+	}
+}
+
+// WithCircuitBreaker sets Opts.BreakerThreshold and Opts.BreakerCooldown.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(o *Opts) {
+		o.BreakerThreshold = threshold
+		o.BreakerCooldown = cooldown
+	}
+}
+
+// WithConcurrency sets Opts.Concurrency.
+func WithConcurrency(n int) Option {
+	return func(o *Opts) {
+		o.Concurrency = n
+	}
+}
+
+// WithRateLimit sets Opts.RateLimit.
+func WithRateLimit(limit RateLimit) Option {
+	return func(o *Opts) {
+		o.RateLimit = limit
+	}
+}
+
+// WithOnError sets Opts.OnError.
+func WithOnError(f func(err error, batch [][]byte)) Option {
+	return func(o *Opts) {
+		o.OnError = f
+	}
+}
+
+// WithOnFlush sets Opts.OnFlush.
+func WithOnFlush(f func(stats FlushStats)) Option {
+	return func(o *Opts) {
+		o.OnFlush = f
+	}
+}
+
+// WithDebugLogger sets Opts.DebugLogger.
+func WithDebugLogger(w io.Writer) Option {
+	return func(o *Opts) {
+		o.DebugLogger = w
+	}
+}
+
+// WithClock sets Opts.Clock.
+func WithClock(clock Clock) Option {
+	return func(o *Opts) {
+		o.Clock = clock
+	}
+}
+
+// WithDeadLetters sets Opts.OnDeadLetter and Opts.MaxDeadLetters.
+func WithDeadLetters(f func(DeadLetter), max int) Option {
+	return func(o *Opts) {
+		o.OnDeadLetter = f
+		o.MaxDeadLetters = max
+	}
+}
+
+// WithMetrics sets Opts.Metrics.
+func WithMetrics(m Metrics) Option {
+	return func(o *Opts) {
+		o.Metrics = m
+	}
+}
+
+// WithFallback sets Opts.Fallback.
+func WithFallback(w io.Writer) Option {
+	return func(o *Opts) {
+		o.Fallback = w
+	}
+}
+
+// WithSpool sets Opts.Spool.
+func WithSpool(s Spool) Option {
+	return func(o *Opts) {
+		o.Spool = s
+	}
+}
+
+// WithOffline sets Opts.Offline.
+func WithOffline(offline Offline) Option {
+	return func(o *Opts) {
+		o.Offline = &offline
+	}
+}
+
+// WithRouter sets Opts.Router.
+func WithRouter(router Router) Option {
+	return func(o *Opts) {
+		o.Router = router
+	}
+}
+
+// WithMirrors adds destinations to Opts.Mirrors.
+func WithMirrors(dests ...Destination) Option {
+	return func(o *Opts) {
+		o.Mirrors = append(o.Mirrors, dests...)
+	}
+}
+
+// WithCloseOnExit sets Opts.CloseOnExit.
+func WithCloseOnExit(enabled bool) Option {
+	return func(o *Opts) {
+		o.CloseOnExit = enabled
+	}
+}
@@ -0,0 +1,51 @@
+// Package otel correlates logs with OpenTelemetry traces.
+//
+// When an entry is logged with a context carrying an active span (see
+// logrus.WithContext), its ids are attached to the entry in the format
+// expected by Datadog, so the log shows up alongside the trace:
+//
+//	hook := dogrus.New(apiKey, dogrus.Opts{
+//		Transformers: []dogrus.Transformer{otel.Transformer()},
+//	})
+package otel
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Transformer returns a dogrus.Transformer adding dogrus.TraceIDKey and
+// dogrus.SpanIDKey to entries logged with an active span in their context.
+func Transformer() dogrus.Transformer {
+	return func(e *logrus.Entry) *logrus.Entry {
+		if e.Context == nil {
+			return e
+		}
+
+		sc := trace.SpanContextFromContext(e.Context)
+		if !sc.IsValid() {
+			return e
+		}
+
+		e.Data[dogrus.TraceIDKey] = TraceID(sc.TraceID())
+		e.Data[dogrus.SpanIDKey] = SpanID(sc.SpanID())
+
+		return e
+	}
+}
+
+// TraceID converts an OpenTelemetry trace id to Datadog format: the lower
+// 64 bits as a decimal number.
+func TraceID(id trace.TraceID) string {
+	return strconv.FormatUint(binary.BigEndian.Uint64(id[8:]), 10)
+}
+
+// SpanID converts an OpenTelemetry span id to Datadog format: a decimal
+// number.
+func SpanID(id trace.SpanID) string {
+	return strconv.FormatUint(binary.BigEndian.Uint64(id[:]), 10)
+}
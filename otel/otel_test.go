@@ -0,0 +1,68 @@
+package otel
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	traceID = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	spanID  = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+func TestIDs(t *testing.T) {
+	// the lower 64 bits, 0xa3ce929d0e0e4736
+	if got := TraceID(traceID); got != "11803532876627986230" {
+		t.Errorf("trace id is %s", got)
+	}
+	if got := SpanID(spanID); got != "67667974448284343" {
+		t.Errorf("span id is %s", got)
+	}
+}
+
+func TestTransformer(t *testing.T) {
+	sink := dogrus.NewMemorySink()
+	d := dogrus.New("key", dogrus.Opts{
+		Sink:         sink,
+		Transformers: []dogrus.Transformer{Transformer()},
+	})
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+	logger.AddHook(d)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	logger.WithContext(trace.ContextWithSpanContext(context.Background(), sc)).Info("traced")
+	logger.WithContext(context.Background()).Info("no span")
+	logger.Info("no context")
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := sink.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	if e := entries[0]; e[dogrus.TraceIDKey] != "11803532876627986230" || e[dogrus.SpanIDKey] != "67667974448284343" {
+		t.Errorf("traced entry is %v, want the ids of the span", e)
+	}
+	for _, e := range entries[1:] {
+		if _, ok := e[dogrus.TraceIDKey]; ok {
+			t.Errorf("entry %v has a trace id without a span", e)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package dogrus
+
+import "sync/atomic"
+
+// OverflowPolicy tells the client what to do with a new entry when the queue
+// is full, or when MaxQueuedBytes is reached.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Fire wait until there is room in the queue.
+	// Nothing is lost, but logging is slowed down to the speed of Datadog.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropNewest discards the new entry, keeping the queue as is.
+	OverflowDropNewest
+
+	// OverflowDropOldest discards the oldest entry in the queue to make room
+	// for the new one. When MaxQueuedBytes is reached and the queue is
+	// empty, the bytes are held by the batches being sent, so the new entry
+	// is discarded instead.
+	OverflowDropOldest
+)
+
+// enqueue puts a formatted entry in the queue, following the configured
+// OverflowPolicy if it's full.
+func (c *Client) enqueue(entry []byte) error {
+	c.enqueueMu.RLock()
+	defer c.enqueueMu.RUnlock()
+
+	select {
+	case <-c.closing:
+		return ErrClosed
+	default:
+	}
+
+	rec := c.spool(entry)
+	size := len(rec.data)
+
+	switch c.opts.OverflowPolicy {
+	case OverflowDropNewest:
+		if !c.reserve(size) {
+			c.drop(rec)
+			return nil
+		}
+
+		select {
+		case c.queue <- rec:
+			c.accepted(rec)
+		default:
+			c.unreserve(size)
+			c.drop(rec)
+		}
+		return nil
+
+	case OverflowDropOldest:
+		for !c.reserve(size) {
+			select {
+			case old := <-c.queue:
+				c.dequeued(old)
+				c.unreserve(len(old.data))
+				c.drop(old)
+			default:
+				c.drop(rec)
+				return nil
+			}
+		}
+
+		for {
+			select {
+			case c.queue <- rec:
+				c.accepted(rec)
+				return nil
+			default:
+			}
+
+			// the sender may have emptied the queue in the meantime, so
+			// don't wait for an entry to drop
+			select {
+			case old := <-c.queue:
+				c.dequeued(old)
+				c.unreserve(len(old.data))
+				c.drop(old)
+			default:
+			}
+		}
+	}
+
+	for !c.reserve(size) {
+		select {
+		case <-c.room:
+		case <-c.closing:
+			c.unspool([]record{rec})
+			return ErrClosed
+		}
+	}
+
+	select {
+	case c.queue <- rec:
+		c.accepted(rec)
+		return nil
+	case <-c.closing:
+		c.unreserve(size)
+		c.unspool([]record{rec})
+		return ErrClosed
+	}
+}
+
+// reserve accounts for size bytes about to be held in memory, telling if
+// they fit in MaxQueuedBytes. An entry always fits when nothing else is
+// held, even if it's larger.
+func (c *Client) reserve(size int) bool {
+	for {
+		held := atomic.LoadInt64(&c.heldBytes)
+		if c.opts.MaxQueuedBytes > 0 && held > 0 && held+int64(size) > int64(c.opts.MaxQueuedBytes) {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(&c.heldBytes, held, held+int64(size)) {
+			break
+		}
+	}
+
+	if c.opts.MaxQueuedBytes > 0 {
+		// other writers may be waiting for room as well
+		c.signalRoom()
+	}
+
+	return true
+}
+
+// unreserve gives back size bytes reserved by reserve, waking up a writer
+// waiting for room.
+func (c *Client) unreserve(size int) {
+	atomic.AddInt64(&c.heldBytes, -int64(size))
+	c.signalRoom()
+}
+
+// settle gives back the bytes of batch once it's been handled.
+func (c *Client) settle(batch []record) {
+	size := 0
+	for _, rec := range batch {
+		size += len(rec.data)
+	}
+
+	if size > 0 {
+		c.unreserve(size)
+	}
+}
+
+func (c *Client) signalRoom() {
+	select {
+	case c.room <- struct{}{}:
+	default:
+	}
+}
+
+// accepted accounts for rec being added to the queue.
+func (c *Client) accepted(rec record) {
+	atomic.AddUint64(&c.enqueued, 1)
+	atomic.AddInt64(&c.queuedBytes, int64(len(rec.data)))
+	c.opts.Metrics.QueueDepth(len(c.queue))
+	c.checkPressure()
+}
+
+// dequeued accounts for rec being taken out of the queue.
+func (c *Client) dequeued(rec record) {
+	atomic.AddInt64(&c.queuedBytes, -int64(len(rec.data)))
+	c.opts.Metrics.QueueDepth(len(c.queue))
+	c.checkPressure()
+}
+
+// drop accounts for rec being discarded.
+func (c *Client) drop(rec record) {
+	c.debugf("queue is full, dropped an entry of %d bytes", len(rec.data))
+	atomic.AddUint64(&c.dropped, 1)
+	c.opts.Metrics.Dropped(1)
+	c.fallback(rec.data)
+	c.unspool([]record{rec})
+}
@@ -0,0 +1,278 @@
+package dogrus
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stalledIntake returns a server holding every request until unblock is
+// called, and a channel receiving a value when a request arrives.
+func stalledIntake(t *testing.T) (url string, arrived <-chan struct{}, unblock func()) {
+	block := make(chan struct{})
+	requests := make(chan struct{}, 100)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- struct{}{}
+		<-block
+	}))
+
+	var once sync.Once
+	unblock = func() { once.Do(func() { close(block) }) }
+	t.Cleanup(func() {
+		unblock()
+		srv.Close()
+	})
+
+	return srv.URL, requests, unblock
+}
+
+func waitRequest(t *testing.T, arrived <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-arrived:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no request reached the intake")
+	}
+}
+
+// entry27 is an entry of 27 bytes.
+var entry27 = []byte(`{"a":"0123456789012345678"}`)
+
+// inflight returns a client capped at 100 bytes whose first batch, 81 bytes,
+// is stuck in flight.
+func inflight(t *testing.T, policy OverflowPolicy, fallback *syncBuffer) (*Client, func()) {
+	url, arrived, unblock := stalledIntake(t)
+
+	opts := Opts{
+		PostURL:        url,
+		MaxQueuedBytes: 100,
+		OverflowPolicy: policy,
+		MaxBatchSize:   3,
+	}
+	if fallback != nil {
+		opts.Fallback = fallback
+	}
+
+	c := NewClient("key", opts)
+	t.Cleanup(func() {
+		unblock()
+		c.Close()
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := c.Enqueue(entry27); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitRequest(t, arrived)
+
+	if held := c.Stats().HeldBytes; held != 81 {
+		t.Fatalf("HeldBytes is %d, want 81", held)
+	}
+
+	return c, unblock
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestMaxQueuedBytesDropNewest(t *testing.T) {
+	c, unblock := inflight(t, OverflowDropNewest, nil)
+
+	for i := 0; i < 5; i++ {
+		c.Enqueue(entry27)
+	}
+
+	s := c.Stats()
+	if s.Dropped != 5 || s.HeldBytes != 81 {
+		t.Fatalf("Dropped %d HeldBytes %d, want 5 and 81", s.Dropped, s.HeldBytes)
+	}
+
+	unblock()
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = c.Stats()
+	if s.Sent != 3 || s.HeldBytes != 0 {
+		t.Fatalf("Sent %d HeldBytes %d, want 3 and 0", s.Sent, s.HeldBytes)
+	}
+}
+
+func TestMaxQueuedBytesDropOldest(t *testing.T) {
+	url, arrived, unblock := stalledIntake(t)
+
+	var fallback syncBuffer
+	c := NewClient("key", Opts{
+		PostURL:        url,
+		MaxQueuedBytes: 100,
+		OverflowPolicy: OverflowDropOldest,
+		MaxBatchSize:   2,
+		Fallback:       &fallback,
+	})
+	t.Cleanup(func() {
+		unblock()
+		c.Close()
+	})
+
+	c.Enqueue(entry27)
+	c.Enqueue(entry27)
+	waitRequest(t, arrived)
+
+	// 54 bytes in flight, the queue has room for one more entry only
+	c.Enqueue([]byte(`{"a":"old-012345678901234"}`))
+	c.Enqueue([]byte(`{"a":"new-012345678901234"}`))
+
+	s := c.Stats()
+	if s.Dropped != 1 || s.HeldBytes != 81 {
+		t.Fatalf("Dropped %d HeldBytes %d, want 1 and 81", s.Dropped, s.HeldBytes)
+	}
+	if got := fallback.String(); got != `{"a":"old-012345678901234"}`+"\n" {
+		t.Fatalf("dropped %q, want the oldest entry", got)
+	}
+}
+
+func TestMaxQueuedBytesDropOldestEmptyQueue(t *testing.T) {
+	var fallback syncBuffer
+	c, _ := inflight(t, OverflowDropOldest, &fallback)
+
+	// the queue is empty, the bytes are held by the batch in flight
+	newest := []byte(`{"a":"new-012345678901234"}`)
+	if err := c.Enqueue(newest); err != nil {
+		t.Fatal(err)
+	}
+
+	s := c.Stats()
+	if s.Dropped != 1 || s.HeldBytes != 81 {
+		t.Fatalf("Dropped %d HeldBytes %d, want 1 and 81", s.Dropped, s.HeldBytes)
+	}
+	if got := fallback.String(); got != string(newest)+"\n" {
+		t.Fatalf("dropped %q, want the new entry", got)
+	}
+}
+
+func TestMaxQueuedBytesBlock(t *testing.T) {
+	c, unblock := inflight(t, OverflowBlock, nil)
+
+	done := make(chan error)
+	go func() {
+		done <- c.Enqueue(entry27)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Enqueue didn't wait for room")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unblock()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Enqueue still blocked after the batch was delivered")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := c.Stats()
+	if s.Sent != 4 || s.Dropped != 0 || s.HeldBytes != 0 {
+		t.Fatalf("Sent %d Dropped %d HeldBytes %d, want 4, 0 and 0", s.Sent, s.Dropped, s.HeldBytes)
+	}
+}
+
+func TestMaxQueuedBytesBlockClosed(t *testing.T) {
+	c, unblock := inflight(t, OverflowBlock, nil)
+
+	done := make(chan error)
+	go func() {
+		done <- c.Enqueue(entry27)
+	}()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unblock()
+	}()
+	c.Close()
+
+	if err := <-done; err != nil && err != ErrClosed {
+		t.Fatal(err)
+	}
+}
+
+// blockingSpool holds Append until release is closed, signalling entered
+// when it's called.
+type blockingSpool struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingSpool) Append(entry []byte) (uint64, error) {
+	close(s.entered)
+	<-s.release
+	return 1, nil
+}
+
+func (s *blockingSpool) Ack(ids ...uint64) error { return nil }
+
+func (s *blockingSpool) Pending() ([]uint64, [][]byte, error) { return nil, nil, nil }
+
+func TestEnqueueWhileClosing(t *testing.T) {
+	sink := NewMemorySink()
+	spool := &blockingSpool{entered: make(chan struct{}), release: make(chan struct{})}
+	c := NewClient("key", Opts{Sink: sink, Spool: spool})
+
+	enqueued := make(chan error, 1)
+	go func() { enqueued <- c.Enqueue([]byte(`{"message":"hello"}`)) }()
+	<-spool.entered
+
+	closed := make(chan error, 1)
+	go func() { closed <- c.Close() }()
+
+	// the entry is past the closing check, Close has to wait for it
+	select {
+	case <-closed:
+		t.Fatal("Close returned while an entry was being enqueued")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(spool.release)
+
+	err := <-enqueued
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+
+	want := 1
+	if err == ErrClosed {
+		want = 0
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(sink.Entries()); n != want {
+		t.Fatalf("Enqueue returned %v and %d entries were delivered", err, n)
+	}
+}
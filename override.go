@@ -0,0 +1,68 @@
+package dogrus
+
+import (
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Fields overriding the metadata of a single entry, so one process can log
+// on behalf of several services. They are removed from the entry and
+// replace ServiceKey, SourceKey and HostnameKey, while tags are added to
+// the ones of the hook. Tags can be a comma separated string or a []string.
+const (
+	OverrideServiceKey  = "dd.service"
+	OverrideSourceKey   = "dd.source"
+	OverrideHostnameKey = "dd.hostname"
+	OverrideTagsKey     = "dd.tags"
+)
+
+// overrides maps each override field to the attribute it replaces.
+var overrides = map[string]string{
+	OverrideServiceKey:  ServiceKey,
+	OverrideSourceKey:   SourceKey,
+	OverrideHostnameKey: HostnameKey,
+}
+
+// hasOverrides tells if entry has any override field.
+func hasOverrides(entry *logrus.Entry) bool {
+	for k := range overrides {
+		if _, ok := entry.Data[k]; ok {
+			return true
+		}
+	}
+
+	_, ok := entry.Data[OverrideTagsKey]
+	return ok
+}
+
+// override applies the override fields of e, before it's enriched with the
+// hook metadata.
+func (d *Hook) override(e *logrus.Entry) {
+	for k, attr := range overrides {
+		if v, ok := e.Data[k]; ok {
+			delete(e.Data, k)
+			e.Data[attr] = v
+		}
+	}
+
+	v, ok := e.Data[OverrideTagsKey]
+	if !ok {
+		return
+	}
+	delete(e.Data, OverrideTagsKey)
+
+	var tags []string
+	switch v := v.(type) {
+	case string:
+		tags = []string{v}
+	case []string:
+		tags = v
+	}
+
+	if d.client.tags != "" {
+		tags = append([]string{d.client.tags}, tags...)
+	}
+
+	e.Data[TagsKey] = strings.Join(tags, ",")
+}
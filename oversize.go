@@ -0,0 +1,119 @@
+package dogrus
+
+import (
+	"errors"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrEntryTooLarge is reported when a formatted entry exceeds MaxEntryBytes
+// and can't be truncated.
+var ErrEntryTooLarge = errors.New("dogrus: log entry too large")
+
+// TruncatedKey is the field added to entries that have been shortened to fit
+// in MaxEntryBytes.
+const TruncatedKey = "dogrus.truncated"
+
+// OversizePolicy tells the hook what to do with entries larger than
+// MaxEntryBytes.
+type OversizePolicy int
+
+const (
+	// OversizeTruncate shortens the message and the longest string fields
+	// until the entry fits, marking it with TruncatedKey.
+	OversizeTruncate OversizePolicy = iota
+
+	// OversizeReject discards the entry, reporting ErrEntryTooLarge to
+	// OnError and counting it in Stats.Dropped.
+	OversizeReject
+)
+
+// truncationSuffix is appended to shortened strings.
+const truncationSuffix = "..."
+
+// fit shortens the formatted entry if it's larger than MaxEntryBytes and
+// OversizePolicy allows it. Entries that are still too large are rejected by
+// Client.Enqueue.
+func (d *Hook) fit(entry *logrus.Entry, formatted []byte) []byte {
+	if len(formatted) <= d.opts.MaxEntryBytes || d.opts.OversizePolicy != OversizeTruncate {
+		return formatted
+	}
+
+	truncated, ok := d.truncate(entry)
+	if !ok {
+		return formatted
+	}
+
+	return truncated
+}
+
+// truncate shortens the longest strings of entry, one at a time, until
+// its formatted size fits in MaxEntryBytes.
+func (d *Hook) truncate(entry *logrus.Entry) ([]byte, bool) {
+	e := copyEntry(entry)
+	e.Data[TruncatedKey] = true
+
+	for {
+		formatted, err := d.opts.Formatter.Format(e)
+		if err != nil {
+			return nil, false
+		}
+
+		excess := len(formatted) - d.opts.MaxEntryBytes
+		if excess <= 0 {
+			return formatted, true
+		}
+
+		// find the longest string, starting from the message
+		key, longest := "", e.Message
+		for k, v := range e.Data {
+			if s, ok := v.(string); ok && len(s) > len(longest) {
+				key, longest = k, s
+			}
+		}
+
+		keep := len(longest) - excess - len(truncationSuffix)
+		if keep <= 0 {
+			if len(longest) <= len(truncationSuffix) {
+				// there is nothing left to shorten
+				return nil, false
+			}
+			keep = 0
+		}
+
+		// don't cut a multi-byte character in half
+		for keep > 0 && !utf8.RuneStart(longest[keep]) {
+			keep--
+		}
+
+		shortened := longest[:keep] + truncationSuffix
+		if key == "" {
+			e.Message = shortened
+		} else {
+			e.Data[key] = shortened
+		}
+	}
+}
+
+// oversized discards an entry larger than MaxEntryBytes, counting it like
+// the entries dropped because the queue is full.
+func (c *Client) oversized(entry []byte) error {
+	atomic.AddUint64(&c.dropped, 1)
+	c.opts.Metrics.Dropped(1)
+	c.fallback(entry)
+
+	return c.reject(ErrEntryTooLarge, entry)
+}
+
+// reject reports to OnError a single entry that won't be sent.
+func (c *Client) reject(err error, entry []byte) error {
+	c.debugf("rejected an entry of %d bytes: %v", len(entry), err)
+
+	if c.opts.OnError != nil {
+		c.opts.OnError(err, [][]byte{entry})
+	}
+
+	return err
+}
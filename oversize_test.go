@@ -0,0 +1,119 @@
+package dogrus
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dropCounter is a Metrics counting the dropped entries.
+type dropCounter struct {
+	nopMetrics
+	dropped int
+}
+
+func (m *dropCounter) Dropped(n int) {
+	m.dropped += n
+}
+
+func TestTruncateAtLimit(t *testing.T) {
+	d := newTestHook(t, Opts{})
+
+	e := d.prepare(logrus.New().WithField("user", "alice"), 1)
+	e.Message = strings.Repeat("a", 100)
+	formatted, err := d.format(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.opts.MaxEntryBytes = len(formatted)
+	if got := d.fit(e, formatted); string(got) != string(formatted) {
+		t.Fatalf("an entry of exactly MaxEntryBytes has been changed: %s", got)
+	}
+
+	d.opts.MaxEntryBytes = len(formatted) - 1
+	got := d.fit(e, formatted)
+	if len(got) > d.opts.MaxEntryBytes {
+		t.Fatalf("truncated to %d bytes, want at most %d", len(got), d.opts.MaxEntryBytes)
+	}
+	if !strings.Contains(string(got), `"`+TruncatedKey+`":true`) {
+		t.Errorf("%s is missing: %s", TruncatedKey, got)
+	}
+	if !strings.Contains(string(got), `..."`) {
+		t.Errorf("the message isn't marked as shortened: %s", got)
+	}
+}
+
+func TestTruncateMultiByteRunes(t *testing.T) {
+	d := newTestHook(t, Opts{})
+
+	e := d.prepare(logrus.NewEntry(logrus.New()), 1)
+	e.Message = strings.Repeat("é世", 50)
+	formatted, err := d.format(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every cut point within the runes of the message
+	for max := len(formatted) - 1; max > len(formatted)-20; max-- {
+		d.opts.MaxEntryBytes = max
+
+		got := d.fit(e, formatted)
+		if len(got) > max {
+			t.Fatalf("MaxEntryBytes %d: truncated to %d bytes", max, len(got))
+		}
+		if !utf8.Valid(got) || strings.ContainsRune(string(got), utf8.RuneError) {
+			t.Fatalf("MaxEntryBytes %d: a rune has been cut: %q", max, got)
+		}
+	}
+
+	if e.Message != strings.Repeat("é世", 50) {
+		t.Error("the original entry has been modified")
+	}
+}
+
+func TestOversizedEntriesCounted(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy OversizePolicy
+		fields logrus.Fields
+	}{
+		{"rejected", OversizeReject, logrus.Fields{"user": strings.Repeat("a", 200)}},
+		{"not truncatable", OversizeTruncate, logrus.Fields{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				metrics dropCounter
+				reports int
+			)
+			d := newTestHook(t, Opts{
+				MaxEntryBytes:  60,
+				OversizePolicy: tt.policy,
+				Metrics:        &metrics,
+				OnError: func(err error, _ [][]byte) {
+					if err == ErrEntryTooLarge {
+						reports++
+					}
+				},
+			})
+
+			entry := logrus.New().WithFields(tt.fields)
+			entry.Level = logrus.InfoLevel
+			entry.Message = "hi"
+			if err := d.Fire(entry); err != ErrEntryTooLarge {
+				t.Fatalf("Fire returned %v, want ErrEntryTooLarge", err)
+			}
+
+			if s := d.Stats(); s.Dropped != 1 || s.Enqueued != 0 {
+				t.Errorf("Dropped %d Enqueued %d, want 1 and 0", s.Dropped, s.Enqueued)
+			}
+			if metrics.dropped != 1 || reports != 1 {
+				t.Errorf("Metrics.Dropped %d ErrEntryTooLarge reports %d, want 1 and 1", metrics.dropped, reports)
+			}
+		})
+	}
+}
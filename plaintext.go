@@ -0,0 +1,45 @@
+package dogrus
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// plainParams are the metadata sent as query parameters with PlainText
+// bodies, the only ones Datadog reads from the request URL.
+var plainParams = []string{ServiceKey, HostnameKey, TagsKey}
+
+// plainURL returns postURL with the metadata that PlainText entries can't
+// carry as query parameters.
+func plainURL(postURL string, metadata map[string]string) string {
+	u, err := url.Parse(postURL)
+	if err != nil {
+		// the request will fail with the same error
+		return postURL
+	}
+
+	query := u.Query()
+	for _, k := range plainParams {
+		if v, ok := metadata[k]; ok {
+			query.Set(k, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// plainFormatter is the default Formatter with PlainText bodies, writing
+// the level and the message of an entry on a single line. Fields are left
+// out.
+type plainFormatter struct{}
+
+// Format implements logrus.Formatter.
+func (plainFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	// each line is a separate entry for Datadog
+	msg := strings.ReplaceAll(entry.Message, "\n", `\n`)
+
+	return []byte(strings.ToUpper(entry.Level.String()) + " " + msg), nil
+}
@@ -0,0 +1,197 @@
+package dogrus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// bufferPool holds the buffers used to format entries and encode batches,
+// so they don't need to be allocated and grown every time.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// maxPooledBuffer is the capacity above which buffers are not put back in
+// the pool, so a few huge batches don't keep memory busy forever.
+const maxPooledBuffer = 8 * 1024 * 1024
+
+func getBuffer() *bytes.Buffer {
+	b := bufferPool.Get().(*bytes.Buffer)
+	b.Reset()
+	return b
+}
+
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() <= maxPooledBuffer {
+		bufferPool.Put(b)
+	}
+}
+
+var gzipPool = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// entryPool holds the copies of the entries modified by Hook.prepare. In
+// the default configuration every entry is copied to add the hook metadata,
+// so reusing the copy and its map saves most of the allocations of Fire.
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return &logrus.Entry{Data: make(logrus.Fields)}
+	},
+}
+
+// borrowEntry is like copyEntry, but the copy comes from entryPool.
+func borrowEntry(entry *logrus.Entry) *logrus.Entry {
+	e := entryPool.Get().(*logrus.Entry)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+
+	e.Logger = entry.Logger
+	e.Time = entry.Time
+	e.Level = entry.Level
+	e.Caller = entry.Caller
+	e.Message = entry.Message
+	e.Context = entry.Context
+
+	return e
+}
+
+// releaseEntry puts back in entryPool an entry returned by borrowEntry,
+// once nothing refers to it anymore.
+func releaseEntry(e *logrus.Entry) {
+	for k := range e.Data {
+		delete(e.Data, k)
+	}
+
+	data := e.Data
+	*e = logrus.Entry{Data: data}
+	entryPool.Put(e)
+}
+
+// format formats entry into a pooled buffer, which formatters like
+// logrus.JSONFormatter use when set as entry.Buffer, returning a copy of
+// exactly the formatted size.
+func (d *Hook) format(entry *logrus.Entry) ([]byte, error) {
+	buffer := getBuffer()
+	defer putBuffer(buffer)
+
+	previous := entry.Buffer
+	entry.Buffer = buffer
+	formatted, err := d.opts.Formatter.Format(entry)
+	entry.Buffer = previous
+
+	if err != nil {
+		return nil, err
+	}
+
+	if buffer.Len() == 0 {
+		// the formatter didn't use the buffer, the result is its own
+		return formatted, nil
+	}
+
+	return append([]byte(nil), formatted...), nil
+}
+
+// payload is an encoded batch, held either in a pooled buffer or, to avoid
+// copying the entries, as segments read one after the other. The buffer is
+// put back in the pool once the sender and every request body reading it
+// are done with it: the HTTP transport can close a request body after the
+// request returned.
+type payload struct {
+	buffer   *bytes.Buffer
+	segments [][]byte
+	size     int
+	refs     int32
+}
+
+func newPayload() *payload {
+	return &payload{buffer: getBuffer(), refs: 1}
+}
+
+// Bytes returns the encoded batch held in the buffer.
+func (p *payload) Bytes() []byte {
+	if p == nil || p.buffer == nil {
+		return nil
+	}
+
+	return p.buffer.Bytes()
+}
+
+// Len returns the size of the encoded batch.
+func (p *payload) Len() int {
+	if p == nil {
+		return 0
+	}
+
+	if p.buffer == nil {
+		return p.size
+	}
+
+	return p.buffer.Len()
+}
+
+// body returns a request body reading the payload.
+func (p *payload) body() io.ReadCloser {
+	if p.buffer == nil {
+		return io.NopCloser(&segmentReader{segments: p.segments})
+	}
+
+	atomic.AddInt32(&p.refs, 1)
+	return &payloadBody{Reader: bytes.NewReader(p.Bytes()), p: p}
+}
+
+// release gives up a reference to the payload.
+func (p *payload) release() {
+	if p != nil && p.buffer != nil && atomic.AddInt32(&p.refs, -1) == 0 {
+		putBuffer(p.buffer)
+	}
+}
+
+type payloadBody struct {
+	*bytes.Reader
+	p    *payload
+	once sync.Once
+}
+
+func (b *payloadBody) Close() error {
+	b.once.Do(b.p.release)
+	return nil
+}
+
+// segmentReader reads segments one after the other, like io.MultiReader
+// without allocating a reader for each of them.
+type segmentReader struct {
+	segments [][]byte
+	offset   int
+}
+
+func (r *segmentReader) Read(p []byte) (int, error) {
+	n := 0
+
+	for n < len(p) && len(r.segments) > 0 {
+		c := copy(p[n:], r.segments[0][r.offset:])
+		n += c
+		r.offset += c
+
+		if r.offset == len(r.segments[0]) {
+			r.segments = r.segments[1:]
+			r.offset = 0
+		}
+	}
+
+	if n == 0 && len(r.segments) == 0 {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
@@ -0,0 +1,148 @@
+package dogrus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// discardSink accepts and forgets every batch.
+type discardSink struct{}
+
+func (discardSink) Send(context.Context, [][]byte) error { return nil }
+
+func benchmarkEntry() *logrus.Entry {
+	return logrus.New().WithFields(logrus.Fields{
+		"user":    "alice",
+		"order":   12345,
+		"elapsed": 0.25,
+	})
+}
+
+func TestPreparedEntriesReused(t *testing.T) {
+	d := newTestHook(t, Opts{Service: "checkout"})
+
+	first := d.prepare(logrus.New().WithField("user", "alice"), 1)
+	releaseEntry(first)
+
+	second := d.prepare(logrus.New().WithField("order", 1), 1)
+	defer releaseEntry(second)
+
+	if _, ok := second.Data["user"]; ok {
+		t.Error("a reused entry kept the fields of the previous one")
+	}
+	if second.Data["order"] != 1 || second.Data[ServiceKey] != "checkout" {
+		t.Errorf("prepared entry is %v", second.Data)
+	}
+}
+
+// BenchmarkFire measures Fire with the default configuration, where every
+// entry is copied to add the hook metadata.
+func BenchmarkFire(b *testing.B) {
+	d := New("key", Opts{Sink: discardSink{}, Service: "checkout"})
+	defer d.Close()
+
+	entry := benchmarkEntry()
+	entry.Message = "order placed"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := d.Fire(entry); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPrepare compares the pooled copy of prepared entries with
+// allocating a new one every time, as done before entryPool.
+func BenchmarkPrepare(b *testing.B) {
+	d := New("key", Opts{Sink: discardSink{}, Service: "checkout"})
+	defer d.Close()
+
+	entry := benchmarkEntry()
+	entry.Data[logrus.ErrorKey] = errors.New("boom")
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			releaseEntry(d.prepare(entry, 1))
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e := copyEntry(entry)
+			d.process(e, 1)
+		}
+	})
+}
+
+// BenchmarkFormat compares formatting into a pooled buffer with letting
+// the formatter allocate its own.
+func BenchmarkFormat(b *testing.B) {
+	d := New("key", Opts{Sink: discardSink{}})
+	defer d.Close()
+
+	entry := benchmarkEntry()
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.format(entry); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.opts.Formatter.Format(entry); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkEncode compares encoding a compressed batch into pooled buffers
+// and gzip writers with allocating new ones for every batch.
+func BenchmarkEncode(b *testing.B) {
+	c := NewClient("key", Opts{Compress: true, PostURL: "http://127.0.0.1:1"})
+	defer c.Close()
+
+	batch := make([]record, 100)
+	for i := range batch {
+		batch[i] = record{data: []byte(`{"message":"order placed","user":"alice","order":12345}`)}
+	}
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			body, _, err := c.encode(batch)
+			if err != nil {
+				b.Fatal(err)
+			}
+			body.release()
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var body bytes.Buffer
+			w := gzip.NewWriter(&body)
+			segments, _ := arraySegments(batch)
+			for _, segment := range segments {
+				w.Write(segment)
+			}
+			if err := w.Close(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package dogrus
+
+import "github.com/sirupsen/logrus"
+
+// Transformer modifies an entry before it's formatted, returning the entry
+// to be sent or nil to skip it.
+// The entry is a copy owned by the hook, so it can be modified in place.
+type Transformer func(*logrus.Entry) *logrus.Entry
+
+// prepare returns the entry to be formatted, after running it through the
+// configured processing steps: transformers, field filtering, error and
+// caller mapping, scrubbing, metadata overrides and enrichment. It returns
+// nil if the entry must be skipped.
+// Fields are filtered before the hook adds its own attributes, so
+// AllowFields doesn't remove them.
+// The original entry is shared with other hooks, so it's copied before being
+// modified. Without Transformers, which may keep the copy, it's a pooled
+// entry to be given back with releaseEntry once sent.
+func (d *Hook) prepare(entry *logrus.Entry, sampleRate float64) *logrus.Entry {
+	if !d.rewrites() && !d.hasError(entry) && !d.hasCaller(entry) && !hasOverrides(entry) && sampleRate >= 1 {
+		return entry
+	}
+
+	if len(d.opts.Transformers) == 0 {
+		e := borrowEntry(entry)
+		d.process(e, sampleRate)
+		return e
+	}
+
+	e := copyEntry(entry)
+
+	for _, transform := range d.opts.Transformers {
+		e = transform(e)
+		if e == nil {
+			return nil
+		}
+
+		if e.Data == nil {
+			e.Data = make(logrus.Fields)
+		}
+	}
+
+	d.process(e, sampleRate)
+
+	return e
+}
+
+// process runs the processing steps following the transformers on e.
+func (d *Hook) process(e *logrus.Entry, sampleRate float64) {
+	d.filterFields(e)
+	d.mapError(e)
+	d.mapCaller(e)
+	d.scrub(e)
+	d.override(e)
+	d.enrich(e, sampleRate)
+}
+
+// rewrites tells if any processing step may modify the entries.
+func (d *Hook) rewrites() bool {
+	return len(d.opts.Transformers) > 0 ||
+		len(d.client.metadata) > 0 || d.runtime != nil ||
+		len(d.opts.Statuses) > 0 ||
+		d.denied != nil || d.allowed != nil ||
+		len(d.opts.ScrubRules) > 0
+}
+
+// copyEntry returns a copy of entry that can be modified without affecting
+// the original entry.
+func copyEntry(entry *logrus.Entry) *logrus.Entry {
+	data := make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+
+	return &logrus.Entry{
+		Logger:  entry.Logger,
+		Data:    data,
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Caller:  entry.Caller,
+		Message: entry.Message,
+		Context: entry.Context,
+	}
+}
@@ -0,0 +1,32 @@
+package dogrus
+
+import "github.com/sirupsen/logrus"
+
+// EnqueuePriority is like Enqueue, but the pending batch is sent right away
+// along with the record, instead of waiting for it to fill up or for
+// FlushPeriod. It's meant for high-severity records, see
+// Opts.PriorityLevels.
+func (c *Client) EnqueuePriority(entry []byte) error {
+	err := c.Enqueue(entry)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case c.urgent <- struct{}{}:
+	default:
+		// a flush is already due, it will include the record
+	}
+
+	return nil
+}
+
+// enqueuer returns the method putting entries at level in the queue of a
+// client.
+func (d *Hook) enqueuer(level logrus.Level) func(*Client, []byte) error {
+	if d.priorities[level] {
+		return (*Client).EnqueuePriority
+	}
+
+	return (*Client).Enqueue
+}
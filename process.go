@@ -0,0 +1,100 @@
+package dogrus
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Attributes describing the process, added with Opts.ProcessAttributes.
+const (
+	ProcessIDKey         = "process.pid"
+	ProcessExecutableKey = "process.executable"
+	GoVersionKey         = "process.go_version"
+	AppVersionKey        = "process.version"
+)
+
+// Attributes describing the Go runtime when the entry was logged, added
+// with Opts.RuntimeStats.
+const (
+	GoroutinesKey = "runtime.goroutines"
+	HeapBytesKey  = "runtime.heap_bytes"
+)
+
+// processAttributes adds the attributes describing the process to m.
+// The app version is Opts.Version, or the one of the main module if the
+// binary has been built with module support.
+func processAttributes(m map[string]string, version string) {
+	if version == "" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+
+	if version != "" {
+		m[AppVersionKey] = version
+	}
+
+	m[ProcessIDKey] = strconv.Itoa(os.Getpid())
+	m[GoVersionKey] = runtime.Version()
+
+	if exe, err := os.Executable(); err == nil {
+		m[ProcessExecutableKey] = filepath.Base(exe)
+	}
+}
+
+// runtimeStatsPeriod is how often the runtime stats are read again, so
+// entries logged in a burst share the same values.
+const runtimeStatsPeriod = time.Second
+
+// runtimeStats caches the goroutine count and heap size.
+type runtimeStats struct {
+	mu         sync.Mutex
+	read       time.Time
+	goroutines int
+	heap       uint64
+	samples    []metrics.Sample
+}
+
+func newRuntimeStats() *runtimeStats {
+	return &runtimeStats{
+		samples: []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}},
+	}
+}
+
+// get returns the goroutine count and the bytes taken by heap objects, read
+// at most runtimeStatsPeriod ago.
+func (r *runtimeStats) get() (int, uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); now.Sub(r.read) >= runtimeStatsPeriod {
+		r.read = now
+		r.goroutines = runtime.NumGoroutine()
+
+		metrics.Read(r.samples)
+		if r.samples[0].Value.Kind() == metrics.KindUint64 {
+			r.heap = r.samples[0].Value.Uint64()
+		}
+	}
+
+	return r.goroutines, r.heap
+}
+
+// addRuntimeStats adds the runtime stats to e, if enabled.
+func (d *Hook) addRuntimeStats(e *logrus.Entry) {
+	if d.runtime == nil {
+		return
+	}
+
+	goroutines, heap := d.runtime.get()
+	setDefault(e, GoroutinesKey, goroutines)
+	setDefault(e, HeapBytesKey, heap)
+}
@@ -0,0 +1,133 @@
+package dogrus
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is reported for batches discarded because sending them
+// would exceed Opts.RateLimit and OverflowPolicy doesn't allow to wait.
+var ErrRateLimited = errors.New("dogrus: rate limit exceeded")
+
+// RateLimit caps the outgoing traffic. Zero values mean no limit.
+type RateLimit struct {
+	// RequestsPerSecond is the maximum number of requests per second.
+	RequestsPerSecond float64
+
+	// BytesPerSecond is the maximum number of request body bytes per
+	// second.
+	BytesPerSecond float64
+}
+
+// tokenBucket is a token bucket refilled at rate tokens per second, holding
+// at most one second worth of tokens. Tokens can go negative, so a single
+// request bigger than the bucket only has to wait longer.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// delay returns how long to wait until n tokens are available.
+func (b *tokenBucket) delay(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// rateLimiter limits requests and bytes sent, according to RateLimit.
+type rateLimiter struct {
+	clock    Clock
+	mu       sync.Mutex
+	requests *tokenBucket
+	bytes    *tokenBucket
+}
+
+func newRateLimiter(limit RateLimit, clock Clock) *rateLimiter {
+	now := clock.Now()
+	l := &rateLimiter{clock: clock}
+
+	if limit.RequestsPerSecond > 0 {
+		l.requests = &tokenBucket{rate: limit.RequestsPerSecond, tokens: limit.RequestsPerSecond, last: now}
+	}
+
+	if limit.BytesPerSecond > 0 {
+		l.bytes = &tokenBucket{rate: limit.BytesPerSecond, tokens: limit.BytesPerSecond, last: now}
+	}
+
+	return l
+}
+
+// delay returns how long to wait before making a request with a body of
+// size bytes.
+func (l *rateLimiter) delay(size int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+
+	var wait time.Duration
+	if l.requests != nil {
+		l.requests.refill(now)
+		wait = l.requests.delay(1)
+	}
+
+	if l.bytes != nil {
+		l.bytes.refill(now)
+		if w := l.bytes.delay(float64(size)); w > wait {
+			wait = w
+		}
+	}
+
+	return wait
+}
+
+// take consumes the tokens for a request with a body of size bytes.
+func (l *rateLimiter) take(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+
+	if l.requests != nil {
+		l.requests.refill(now)
+		l.requests.tokens--
+	}
+
+	if l.bytes != nil {
+		l.bytes.refill(now)
+		l.bytes.tokens -= float64(size)
+	}
+}
+
+// throttle waits until a request with a body of size bytes is allowed by
+// Opts.RateLimit. If OverflowPolicy doesn't allow waiting, it returns
+// ErrRateLimited instead.
+func (c *Client) throttle(size int) error {
+	if c.limiter == nil {
+		return nil
+	}
+
+	if wait := c.limiter.delay(size); wait > 0 {
+		if c.opts.OverflowPolicy != OverflowBlock {
+			return ErrRateLimited
+		}
+
+		if !sleep(c.opts.Clock, wait, c.ctx.Done()) {
+			return c.ctx.Err()
+		}
+	}
+
+	c.limiter.take(size)
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package dogrus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := &fixedClock{Clock: SystemClock, now: time.Now()}
+
+	t.Run("requests", func(t *testing.T) {
+		l := newRateLimiter(RateLimit{RequestsPerSecond: 2}, clock)
+
+		for i := 0; i < 2; i++ {
+			if wait := l.delay(0); wait != 0 {
+				t.Fatalf("request %d waits %s within the burst", i, wait)
+			}
+			l.take(0)
+		}
+
+		if wait := l.delay(0); wait != 500*time.Millisecond {
+			t.Fatalf("waits %s after the burst, want 500ms", wait)
+		}
+
+		clock.now = clock.now.Add(500 * time.Millisecond)
+		if wait := l.delay(0); wait != 0 {
+			t.Fatalf("waits %s once a token is refilled", wait)
+		}
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		l := newRateLimiter(RateLimit{BytesPerSecond: 100}, clock)
+
+		// a request bigger than the bucket waits for the missing tokens,
+		// leaving it negative for the next ones
+		if wait := l.delay(250); wait != 1500*time.Millisecond {
+			t.Fatalf("waits %s for a request bigger than the bucket, want 1.5s", wait)
+		}
+		l.take(250)
+
+		if wait := l.delay(10); wait != 1600*time.Millisecond {
+			t.Fatalf("waits %s after the large request, want 1.6s", wait)
+		}
+
+		// the bucket holds at most one second worth of tokens
+		clock.now = clock.now.Add(time.Hour)
+		if wait := l.delay(150); wait != 500*time.Millisecond {
+			t.Fatalf("waits %s after a long idle, want 500ms", wait)
+		}
+	})
+}
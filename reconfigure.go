@@ -0,0 +1,93 @@
+package dogrus
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetFlushPeriod changes Opts.FlushPeriod while the client is running. The
+// next periodic flush is rescheduled right away.
+// It's safe to call it concurrently with the client being used.
+func (c *Client) SetFlushPeriod(period time.Duration) {
+	if period <= 0 {
+		return
+	}
+
+	atomic.StoreInt64(&c.period, int64(period))
+	c.reconfigure()
+}
+
+// SetMaxBatchSize changes Opts.MaxBatchSize while the client is running.
+// It's safe to call it concurrently with the client being used.
+func (c *Client) SetMaxBatchSize(size int) {
+	if size <= 0 {
+		return
+	}
+
+	atomic.StoreInt64(&c.batchSize, int64(size))
+	c.reconfigure()
+}
+
+// reconfigure tells the sender loop that the settings changed.
+func (c *Client) reconfigure() {
+	select {
+	case c.reconfigured <- struct{}{}:
+	default:
+		// the loop has yet to pick up a previous change
+	}
+}
+
+// maxBatchSize returns the current MaxBatchSize.
+func (c *Client) maxBatchSize() int {
+	return int(atomic.LoadInt64(&c.batchSize))
+}
+
+// SetFlushPeriod changes Opts.FlushPeriod of every destination of the hook,
+// see Client.SetFlushPeriod.
+func (d *Hook) SetFlushPeriod(period time.Duration) {
+	for _, c := range d.clients() {
+		c.SetFlushPeriod(period)
+	}
+}
+
+// SetMaxBatchSize changes Opts.MaxBatchSize of every destination of the
+// hook, see Client.SetMaxBatchSize.
+func (d *Hook) SetMaxBatchSize(size int) {
+	for _, c := range d.clients() {
+		c.SetMaxBatchSize(size)
+	}
+}
+
+// SetMinLevel stops the hook from sending entries less severe than min,
+// such as debug ones when min is logrus.InfoLevel, until it's called
+// again. Logrus only calls the hook for the levels in Opts.Levels, so they
+// can't be widened this way.
+// It's safe to call it concurrently with the hook being used.
+func (d *Hook) SetMinLevel(min logrus.Level) {
+	atomic.StoreUint32(&d.minLevel, uint32(min))
+}
+
+// SetSampleRates replaces Opts.SampleRates.
+// It's safe to call it concurrently with the hook being used.
+func (d *Hook) SetSampleRates(rates map[logrus.Level]float64) {
+	d.sampleRates.Store(copyRates(rates))
+}
+
+// copyRates returns a copy of rates, so the caller can keep modifying its
+// map while the hook reads the copy concurrently.
+func copyRates(rates map[logrus.Level]float64) map[logrus.Level]float64 {
+	copied := make(map[logrus.Level]float64, len(rates))
+	for level, rate := range rates {
+		copied[level] = rate
+	}
+
+	return copied
+}
+
+// clients returns the default client, the ones of Router destinations and
+// the mirrors.
+func (d *Hook) clients() []*Client {
+	return append(append([]*Client{d.client}, d.routed()...), d.mirrors...)
+}
@@ -0,0 +1,52 @@
+package dogrus
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// maxRetryBackoff caps the backoff between retries with Opts.RetryFor, so
+// an outage ending late in the budget is noticed soon enough.
+const maxRetryBackoff = time.Minute
+
+// canRetry tells if a batch first tried at first, and already tried again
+// retries times, can be tried again after waiting backoff.
+func (c *Client) canRetry(retries int, first time.Time, backoff time.Duration) bool {
+	if c.opts.RetryFor > 0 {
+		return c.opts.Clock.Now().Add(backoff).Sub(first) <= c.opts.RetryFor
+	}
+
+	return retries < c.opts.MaxRetries
+}
+
+// nextBackoff returns the backoff following backoff.
+func (c *Client) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if c.opts.RetryFor > 0 && backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+
+	return backoff
+}
+
+// retain reserves size bytes of Opts.MaxRetryBytes for a batch about to be
+// retried, telling if there is room for it.
+func (c *Client) retain(size int) bool {
+	if c.opts.MaxRetryBytes <= 0 {
+		return true
+	}
+
+	if atomic.AddInt64(&c.retained, int64(size)) > int64(c.opts.MaxRetryBytes) {
+		atomic.AddInt64(&c.retained, -int64(size))
+		return false
+	}
+
+	return true
+}
+
+// release gives back the bytes reserved by retain.
+func (c *Client) release(size int) {
+	if c.opts.MaxRetryBytes > 0 {
+		atomic.AddInt64(&c.retained, -int64(size))
+	}
+}
@@ -0,0 +1,111 @@
+package dogrus
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Destination is a Datadog intake entries can be routed to, such as the one
+// of another organization.
+type Destination struct {
+	// APIKey is the API key of the destination.
+	APIKey string
+
+	// PostURL is the address of the intake.
+	// By default is the PostURL of the hook.
+	PostURL string
+}
+
+// Router picks the Destination of an entry. It's called after the entry is
+// prepared, so it can rely on the fields added by Transformers, like a
+// tenant. Entries routed to the zero Destination go to the hook default
+// one, the API key given to New.
+// The entry must not be retained, the hook reuses it once sent.
+type Router func(entry *logrus.Entry) Destination
+
+// routes holds a Client for every Destination returned by Router, each with
+// its own queue. They are created the first time an entry is routed to
+// them.
+type routes struct {
+	mu      sync.Mutex
+	clients map[Destination]*Client
+	closed  bool
+}
+
+// route returns the Client delivering entry.
+func (d *Hook) route(entry *logrus.Entry) *Client {
+	if d.opts.Router == nil {
+		return d.client
+	}
+
+	dest := d.opts.Router(entry)
+	if dest == (Destination{}) {
+		return d.client
+	}
+
+	d.routes.mu.Lock()
+	defer d.routes.mu.Unlock()
+
+	if d.routes.closed {
+		// the default client rejects the entry
+		return d.client
+	}
+
+	c, ok := d.routes.clients[dest]
+	if !ok {
+		c = d.newDestination(dest)
+
+		if d.routes.clients == nil {
+			d.routes.clients = make(map[Destination]*Client)
+		}
+		d.routes.clients[dest] = c
+	}
+
+	return c
+}
+
+// newDestination creates a Client delivering to dest, configured by the
+// hook Opts.
+func (d *Hook) newDestination(dest Destination) *Client {
+	opts := d.opts
+	opts.APIKeyFunc = nil
+
+	// keep the settings changed at runtime
+	opts.FlushPeriod = time.Duration(atomic.LoadInt64(&d.client.period))
+	opts.MaxBatchSize = d.client.maxBatchSize()
+
+	if dest.PostURL != "" {
+		opts.PostURL = dest.PostURL
+	}
+
+	// the spool is bound to the default destination, entries recovered
+	// from it would be sent to the wrong one
+	opts.Spool = nil
+
+	return NewClient(dest.APIKey, opts)
+}
+
+// routed returns the clients created for the destinations of Router.
+func (d *Hook) routed() []*Client {
+	d.routes.mu.Lock()
+	defer d.routes.mu.Unlock()
+
+	clients := make([]*Client, 0, len(d.routes.clients))
+	for _, c := range d.routes.clients {
+		clients = append(clients, c)
+	}
+
+	return clients
+}
+
+// closeRoutes stops routing entries and returns the clients to be closed.
+func (d *Hook) closeRoutes() []*Client {
+	d.routes.mu.Lock()
+	d.routes.closed = true
+	d.routes.mu.Unlock()
+
+	return d.routed()
+}
@@ -0,0 +1,98 @@
+package dogrus_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+	"github.com/sirupsen/logrus"
+)
+
+func TestRouter(t *testing.T) {
+	def := dogrustest.NewIntake(t, "key")
+	acme := dogrustest.NewIntake(t, "acme-key")
+
+	var routed int
+	d := dogrus.New("key", dogrus.Opts{
+		PostURL: def.URL,
+		Router: func(entry *logrus.Entry) dogrus.Destination {
+			routed++
+			if entry.Data["tenant"] == "acme" {
+				return dogrus.Destination{APIKey: "acme-key", PostURL: acme.URL}
+			}
+			return dogrus.Destination{}
+		},
+	})
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+	logger.AddHook(d)
+
+	logger.WithField("tenant", "acme").Info("one")
+	logger.WithField("tenant", "initech").Info("two")
+	logger.WithField("tenant", "acme").Info("three")
+	logger.Info("four")
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if routed != 4 {
+		t.Errorf("Router called %d times, want once per entry", routed)
+	}
+
+	// the destination client is created once, both entries are in its batch
+	if batches := acme.Batches(); len(batches) != 1 || len(batches[0].Entries) != 2 {
+		t.Fatalf("acme received %d batches, want one with two entries", len(batches))
+	}
+	acme.AssertEntry(t, "message", "one")
+	acme.AssertEntry(t, "message", "three")
+	acme.AssertNoEntry(t, "message", "two")
+
+	def.AssertEntry(t, "message", "two")
+	def.AssertEntry(t, "message", "four")
+	def.AssertNoEntry(t, "message", "one")
+}
+
+func TestMirrors(t *testing.T) {
+	primary := dogrustest.NewIntake(t, "key")
+	mirror := dogrustest.NewIntake(t, "mirror-key")
+
+	d := dogrus.New("key", dogrus.Opts{
+		PostURL:      primary.URL,
+		MaxBatchSize: 1,
+		Mirrors:      []dogrus.Destination{{APIKey: "mirror-key", PostURL: mirror.URL}},
+	})
+
+	logger := logrus.New()
+	logger.Out = io.Discard
+	logger.AddHook(d)
+
+	// a mirror failing doesn't affect the default destination
+	mirror.Fail(http.StatusBadRequest)
+	logger.Info("one")
+	logger.Info("two")
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.AssertEntry(t, "message", "one")
+	primary.AssertEntry(t, "message", "two")
+	mirror.AssertNoEntry(t, "message", "one")
+	mirror.AssertEntry(t, "message", "two")
+
+	if s := d.Stats(); s.Sent != 2 || s.FailedFlushes != 0 {
+		t.Errorf("default destination Sent %d FailedFlushes %d, want 2 and 0", s.Sent, s.FailedFlushes)
+	}
+
+	mirrors := d.Mirrors()
+	if len(mirrors) != 1 {
+		t.Fatalf("got %d mirrors, want 1", len(mirrors))
+	}
+	if s := mirrors[0].Stats(); s.Sent != 1 || s.FailedFlushes != 1 {
+		t.Errorf("mirror Sent %d FailedFlushes %d, want 1 and 1", s.Sent, s.FailedFlushes)
+	}
+}
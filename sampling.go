@@ -0,0 +1,145 @@
+package dogrus
+
+import (
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SampleRateKey is the field added to entries kept by sampling, holding the
+// fraction of entries of the same kind that are sent.
+const SampleRateKey = "dogrus.sample_rate"
+
+// AdaptiveSampling configures a sampler that lowers the fraction of
+// low-severity entries sent to Datadog when log volume spikes.
+type AdaptiveSampling struct {
+	// TargetRate is the number of entries per second the hook should
+	// handle. Above it, entries of SampledLevels are sampled so that the
+	// rate is brought back near the target.
+	TargetRate float64
+
+	// SampledLevels are the levels subject to adaptive sampling.
+	// By default are info, debug and trace.
+	SampledLevels []logrus.Level
+
+	// MinSampleRate is the lowest fraction of entries that is always kept.
+	// By default is 0.01.
+	MinSampleRate float64
+
+	// Window is how often the sample rate is recomputed.
+	// By default is 1 second.
+	Window time.Duration
+}
+
+// adaptiveSampler tracks the rate of entries and the queue usage to compute
+// the current sample rate.
+type adaptiveSampler struct {
+	// accessed atomically
+	count       uint64
+	rate        uint64 // math.Float64bits
+	windowStart int64
+
+	cfg     AdaptiveSampling
+	sampled map[logrus.Level]bool
+	clock   Clock
+}
+
+func newAdaptiveSampler(cfg AdaptiveSampling, clock Clock) *adaptiveSampler {
+	if cfg.SampledLevels == nil {
+		cfg.SampledLevels = []logrus.Level{logrus.InfoLevel, logrus.DebugLevel, logrus.TraceLevel}
+	}
+
+	if cfg.MinSampleRate == 0 {
+		cfg.MinSampleRate = 0.01
+	}
+
+	if cfg.Window == 0 {
+		cfg.Window = time.Second
+	}
+
+	s := &adaptiveSampler{
+		cfg:         cfg,
+		rate:        math.Float64bits(1),
+		clock:       clock,
+		windowStart: clock.Now().UnixNano(),
+		sampled:     make(map[logrus.Level]bool, len(cfg.SampledLevels)),
+	}
+
+	for _, level := range cfg.SampledLevels {
+		s.sampled[level] = true
+	}
+
+	return s
+}
+
+// observe accounts for a new entry and returns the sample rate for its
+// level. queueUsage is the fraction of the queue currently taken.
+func (s *adaptiveSampler) observe(level logrus.Level, queueUsage float64) float64 {
+	count := atomic.AddUint64(&s.count, 1)
+
+	now := s.clock.Now().UnixNano()
+	start := atomic.LoadInt64(&s.windowStart)
+	elapsed := time.Duration(now - start)
+
+	// at the end of the window, a single goroutine recomputes the rate
+	if elapsed >= s.cfg.Window && atomic.CompareAndSwapInt64(&s.windowStart, start, now) {
+		atomic.StoreUint64(&s.count, 0)
+		atomic.StoreUint64(&s.rate, math.Float64bits(s.compute(count, elapsed, queueUsage)))
+	}
+
+	if !s.sampled[level] {
+		return 1
+	}
+
+	return s.current()
+}
+
+// compute returns the sample rate for the next window, given that count
+// entries have been seen in elapsed.
+func (s *adaptiveSampler) compute(count uint64, elapsed time.Duration, queueUsage float64) float64 {
+	rate := 1.0
+
+	observed := float64(count) / elapsed.Seconds()
+	if s.cfg.TargetRate > 0 && observed > s.cfg.TargetRate {
+		rate = s.cfg.TargetRate / observed
+	}
+
+	// the sender is not keeping up, cut further as the queue fills up
+	if queueUsage > 0.5 {
+		rate *= (1 - queueUsage) * 2
+	}
+
+	return math.Max(rate, s.cfg.MinSampleRate)
+}
+
+// current returns the sample rate of the current window.
+func (s *adaptiveSampler) current() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&s.rate))
+}
+
+// sample tells if an entry at level must be kept according to
+// Opts.SampleRates and Opts.AdaptiveSampling, counting the ones left out.
+// It also returns the fraction of entries like this one that are kept.
+func (d *Hook) sample(level logrus.Level) (bool, float64) {
+	rate := 1.0
+	rates, _ := d.sampleRates.Load().(map[logrus.Level]float64)
+	if r, ok := rates[level]; ok && r < 1 {
+		rate = math.Max(r, 0)
+	}
+
+	if d.adaptive != nil {
+		queue := d.client.queue
+		rate *= d.adaptive.observe(level, float64(len(queue))/float64(cap(queue)))
+	}
+
+	if rate >= 1 || rand.Float64() < rate {
+		return true, rate
+	}
+
+	atomic.AddUint64(&d.sampledOut, 1)
+
+	return false, rate
+}
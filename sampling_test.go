@@ -0,0 +1,33 @@
+package dogrus
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSampleRatesCopied(t *testing.T) {
+	rates := map[logrus.Level]float64{logrus.DebugLevel: 0}
+	d := newTestHook(t, Opts{SampleRates: rates})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			rates[logrus.DebugLevel] = 1
+			rates[logrus.InfoLevel] = 0
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		d.sample(logrus.DebugLevel)
+	}
+	<-done
+
+	if keep, _ := d.sample(logrus.DebugLevel); keep {
+		t.Error("the rate has been changed through the caller's map")
+	}
+	if keep, _ := d.sample(logrus.InfoLevel); !keep {
+		t.Error("a rate has been added through the caller's map")
+	}
+}
@@ -0,0 +1,183 @@
+package dogrus
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ScrubRule replaces sensitive data matching Pattern in messages and field
+// values before they leave the process.
+type ScrubRule struct {
+	// Name describes the rule.
+	Name string
+
+	// Pattern matches the data to be replaced.
+	Pattern *regexp.Regexp
+
+	// Replacement replaces every match. It can refer to submatches like
+	// regexp.Regexp.ReplaceAllString does.
+	Replacement string
+
+	// Validate, if set, is called for each match to confirm it must be
+	// replaced, to reduce false positives.
+	Validate func(match string) bool
+}
+
+// Built-in scrubbing rules. DefaultScrubRules returns all of them.
+var (
+	// ScrubEmails replaces email addresses.
+	ScrubEmails = ScrubRule{
+		Name:        "email",
+		Pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		Replacement: "[REDACTED EMAIL]",
+	}
+
+	// ScrubCreditCards replaces credit card numbers, optionally separated
+	// by spaces or dashes, passing the Luhn check.
+	ScrubCreditCards = ScrubRule{
+		Name:        "credit card",
+		Pattern:     regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+		Replacement: "[REDACTED CARD]",
+		Validate:    luhn,
+	}
+
+	// ScrubBearerTokens replaces tokens in Authorization header values.
+	ScrubBearerTokens = ScrubRule{
+		Name:        "bearer token",
+		Pattern:     regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`),
+		Replacement: "$1 [REDACTED]",
+	}
+
+	// ScrubJWTs replaces JSON Web Tokens.
+	ScrubJWTs = ScrubRule{
+		Name:        "jwt",
+		Pattern:     regexp.MustCompile(`\beyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+`),
+		Replacement: "[REDACTED JWT]",
+	}
+)
+
+// DefaultScrubRules returns the built-in scrubbing rules, to be used as
+// Opts.ScrubRules, possibly along with custom ones.
+func DefaultScrubRules() []ScrubRule {
+	return []ScrubRule{
+		ScrubJWTs,
+		ScrubBearerTokens,
+		ScrubEmails,
+		ScrubCreditCards,
+	}
+}
+
+// Apply returns s with every match of the rule replaced.
+func (r ScrubRule) Apply(s string) string {
+	if r.Validate == nil {
+		return r.Pattern.ReplaceAllString(s, r.Replacement)
+	}
+
+	return r.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+		if !r.Validate(match) {
+			return match
+		}
+
+		return r.Pattern.ReplaceAllString(match, r.Replacement)
+	})
+}
+
+// scrub applies Opts.ScrubRules to the message and to the fields of e.
+func (d *Hook) scrub(e *logrus.Entry) {
+	if len(d.opts.ScrubRules) == 0 {
+		return
+	}
+
+	e.Message = d.scrubString(e.Message)
+
+	for k, v := range e.Data {
+		e.Data[k] = d.scrubValue(v)
+	}
+}
+
+// scrubValue returns v with the rules applied to its strings and errors,
+// including the ones nested in maps and slices. Maps and slices are copied
+// rather than modified, since they are shared with the caller.
+func (d *Hook) scrubValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return d.scrubString(v)
+
+	case error:
+		// errors are replaced by their message only when it changes
+		s := v.Error()
+		if scrubbed := d.scrubString(s); scrubbed != s {
+			return scrubbed
+		}
+		return v
+
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			m[k] = d.scrubValue(x)
+		}
+		return m
+
+	case logrus.Fields:
+		m := make(logrus.Fields, len(v))
+		for k, x := range v {
+			m[k] = d.scrubValue(x)
+		}
+		return m
+
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for k, s := range v {
+			m[k] = d.scrubString(s)
+		}
+		return m
+
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, x := range v {
+			s[i] = d.scrubValue(x)
+		}
+		return s
+
+	case []string:
+		s := make([]string, len(v))
+		for i, x := range v {
+			s[i] = d.scrubString(x)
+		}
+		return s
+	}
+
+	return v
+}
+
+func (d *Hook) scrubString(s string) string {
+	for _, rule := range d.opts.ScrubRules {
+		s = rule.Apply(s)
+	}
+
+	return s
+}
+
+// luhn tells if the digits in s pass the Luhn checksum.
+func luhn(s string) bool {
+	s = strings.NewReplacer(" ", "", "-", "").Replace(s)
+
+	sum := 0
+	double := false
+	for i := len(s) - 1; i >= 0; i-- {
+		n := int(s[i] - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+
+		sum += n
+		double = !double
+	}
+
+	return sum%10 == 0
+}
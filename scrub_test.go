@@ -0,0 +1,107 @@
+package dogrus
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestScrubRules(t *testing.T) {
+	password := ScrubRule{
+		Name:        "password",
+		Pattern:     regexp.MustCompile(`(password=)\S+`),
+		Replacement: "${1}***",
+	}
+
+	tests := []struct {
+		name string
+		rule ScrubRule
+		in   string
+		want string
+	}{
+		{"email", ScrubEmails, "contact alice.b+ops@example.co.uk now", "contact [REDACTED EMAIL] now"},
+		{"email without domain", ScrubEmails, "alice@localhost", "alice@localhost"},
+
+		{"card", ScrubCreditCards, "card 4111111111111111 ok", "card [REDACTED CARD] ok"},
+		{"card with spaces", ScrubCreditCards, "4111 1111 1111 1111", "[REDACTED CARD]"},
+		{"card with dashes", ScrubCreditCards, "5500-0000-0000-0004.", "[REDACTED CARD]."},
+		{"card failing Luhn", ScrubCreditCards, "card 4111111111111112", "card 4111111111111112"},
+		{"card in a longer number", ScrubCreditCards, "order 411111111111111100001", "order 411111111111111100001"},
+		{"card in a word", ScrubCreditCards, "id4111111111111111", "id4111111111111111"},
+		{"too few digits", ScrubCreditCards, "ref 4111 1111 1111", "ref 4111 1111 1111"},
+
+		{"bearer token", ScrubBearerTokens, "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{"basic credentials", ScrubBearerTokens, "basic dXNlcjpwYXNz==", "basic [REDACTED]"},
+		{"bearer without token", ScrubBearerTokens, "bearerville", "bearerville"},
+
+		{"jwt", ScrubJWTs, "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig_123", "token [REDACTED JWT]"},
+		{"jwt header only", ScrubJWTs, "eyJhbGciOiJIUzI1NiJ9", "eyJhbGciOiJIUzI1NiJ9"},
+
+		{"submatch replacement", password, "login password=hunter2 user=bob", "login password=*** user=bob"},
+		{"no match", password, "login user=bob", "login user=bob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.Apply(tt.in); got != tt.want {
+				t.Errorf("Apply(%q) is %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScrubNestedFields(t *testing.T) {
+	d := newTestHook(t, Opts{ScrubRules: DefaultScrubRules()})
+
+	user := map[string]interface{}{
+		"email": "alice@example.com",
+		"cards": []interface{}{"4111111111111111", 42},
+		"meta":  logrus.Fields{"jwt": "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig"},
+	}
+	headers := map[string]string{"Authorization": "Bearer abc123"}
+	emails := []string{"bob@example.com", "not an email"}
+	cause := errors.New("no account for alice@example.com")
+
+	entry := logrus.New().WithFields(logrus.Fields{
+		"user":    user,
+		"headers": headers,
+		"emails":  emails,
+		"error":   cause,
+		"other":   errors.New("nothing to hide"),
+		"count":   3,
+	})
+	entry.Message = "charged 4111-1111-1111-1111"
+
+	e := copyEntry(entry)
+	d.scrub(e)
+
+	want := logrus.Fields{
+		"user": map[string]interface{}{
+			"email": "[REDACTED EMAIL]",
+			"cards": []interface{}{"[REDACTED CARD]", 42},
+			"meta":  logrus.Fields{"jwt": "[REDACTED JWT]"},
+		},
+		"headers": map[string]string{"Authorization": "Bearer [REDACTED]"},
+		"emails":  []string{"[REDACTED EMAIL]", "not an email"},
+		"error":   "no account for [REDACTED EMAIL]",
+		"other":   entry.Data["other"],
+		"count":   3,
+	}
+	if !reflect.DeepEqual(e.Data, want) {
+		t.Errorf("scrubbed fields are %v, want %v", e.Data, want)
+	}
+	if e.Message != "charged [REDACTED CARD]" {
+		t.Errorf("scrubbed message is %q", e.Message)
+	}
+
+	// the values shared with the caller are left untouched
+	if user["email"] != "alice@example.com" || user["cards"].([]interface{})[0] != "4111111111111111" {
+		t.Error("the caller's map has been modified")
+	}
+	if headers["Authorization"] != "Bearer abc123" || emails[0] != "bob@example.com" {
+		t.Error("the caller's values have been modified")
+	}
+}
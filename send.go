@@ -0,0 +1,438 @@
+package dogrus
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FlushStats describes a batch successfully delivered to Datadog.
+type FlushStats struct {
+	// Entries is the number of log entries in the batch.
+	Entries int
+
+	// Bytes is the size of the request body, after compression.
+	Bytes int
+
+	// CompressionRatio is the size of the uncompressed body divided by
+	// Bytes. It's 1 when Compress is disabled.
+	CompressionRatio float64
+
+	// Latency is the duration of the request that succeeded.
+	Latency time.Duration
+
+	// Retries is the number of failed attempts before the successful one.
+	Retries int
+}
+
+// BodyFormat is how entries are framed in the body of HTTP requests.
+type BodyFormat int
+
+const (
+	// JSONArray sends the entries as the elements of a JSON array.
+	JSONArray BodyFormat = iota
+
+	// NDJSON sends the entries as newline-delimited JSON, one per line.
+	NDJSON
+
+	// PlainText sends the entries as raw text, one per line, for services
+	// producing unstructured logs. Service, Hostname and the tags are sent
+	// as query parameters, and the default Formatter writes only the level
+	// and the message of logrus entries.
+	PlainText
+)
+
+// send sends a batch of log entries to Datadog server, split in as many
+// requests as needed to respect MaxBatchBytes and MaxEntriesPerRequest.
+// The batch doesn't count anymore against MaxQueuedBytes once it's been
+// delivered, reported as failed or kept in the offline backlog.
+func (c *Client) send(batch []record) error {
+	defer c.settle(batch)
+
+	if c.holding(batch) {
+		return ErrOffline
+	}
+
+	var err error
+
+	for len(batch) > 0 {
+		n := c.chunkLen(batch)
+		e := c.deliver(batch[:n])
+		if e == ErrOffline {
+			// the rest of the batch waits in the backlog
+			c.goOffline(batch)
+			return e
+		}
+		if e != nil {
+			err = e
+		}
+		batch = batch[n:]
+	}
+
+	return err
+}
+
+// chunkLen returns how many entries from the head of batch fit in a single
+// request. It's at least one, even if the first entry alone is too big.
+func (c *Client) chunkLen(batch []record) int {
+	size := 0
+	if c.opts.BodyFormat == JSONArray {
+		size = 1 // opening bracket
+	}
+
+	for i, rec := range batch {
+		if i == c.opts.MaxEntriesPerRequest {
+			return i
+		}
+
+		// each entry is followed by a comma, by the closing bracket or by a
+		// newline
+		size += len(rec.data) + 1
+		if size > c.opts.MaxBatchBytes && i > 0 {
+			return i
+		}
+	}
+
+	return len(batch)
+}
+
+// deliver makes a single request carrying batch, retrying on temporary
+// failures and reporting the outcome to OnFlush or OnError.
+func (c *Client) deliver(batch []record) error {
+	if !c.breaker.allow() {
+		return c.fail(ErrCircuitOpen, batch)
+	}
+	defer c.breaker.done()
+
+	body, rawSize, err := c.encode(batch)
+	defer body.release()
+	if err != nil {
+		return c.fail(err, batch)
+	}
+
+	size := body.Len()
+	if c.opts.Sink != nil {
+		size = rawSize
+	}
+
+	err = c.throttle(size)
+	if err != nil {
+		return c.fail(err, batch)
+	}
+
+	c.debugf("sending %d entries, %d bytes (%d uncompressed)", len(batch), size, rawSize)
+
+	stats := FlushStats{
+		Entries:          len(batch),
+		Bytes:            size,
+		CompressionRatio: 1,
+	}
+	if size > 0 {
+		stats.CompressionRatio = float64(rawSize) / float64(size)
+	}
+
+	backoff := c.opts.RetryBackoff
+	first := c.opts.Clock.Now()
+	retained := false
+
+	for {
+		start := c.opts.Clock.Now()
+		err = c.transmit(batch, body)
+		stats.Latency = c.opts.Clock.Now().Sub(start)
+
+		if err == nil {
+			break
+		}
+
+		if c.disconnected(err) {
+			return ErrOffline
+		}
+
+		if !c.canRetry(stats.Retries, first, backoff) || !c.retryable(err) {
+			c.breaker.failure()
+			return c.fail(err, batch)
+		}
+
+		if !retained {
+			if !c.retain(rawSize) {
+				c.debugf("no room in MaxRetryBytes to retry %d entries", len(batch))
+				c.breaker.failure()
+				return c.fail(err, batch)
+			}
+			retained = true
+			defer c.release(rawSize)
+		}
+
+		c.debugf("attempt %d failed, retrying in %s: %v", stats.Retries+1, backoff, err)
+
+		if !sleep(c.opts.Clock, backoff, c.ctx.Done()) {
+			c.breaker.failure()
+			return c.fail(err, batch)
+		}
+
+		backoff = c.nextBackoff(backoff)
+		stats.Retries++
+		atomic.AddUint64(&c.retries, 1)
+	}
+
+	c.breaker.success()
+	c.unspool(batch)
+
+	atomic.StoreInt64(&c.unreachable, 0)
+	atomic.StoreInt64(&c.failures, 0)
+	atomic.AddUint64(&c.sent, uint64(len(batch)))
+	atomic.StoreInt64(&c.lastFlush, c.opts.Clock.Now().UnixNano())
+
+	c.debugf("delivered %d entries in %s", len(batch), stats.Latency)
+	c.opts.Metrics.Flushed(stats)
+
+	if c.opts.OnFlush != nil {
+		c.opts.OnFlush(stats)
+	}
+
+	return nil
+}
+
+// fail reports to OnError that batch has not been delivered because of err.
+func (c *Client) fail(err error, batch []record) error {
+	atomic.AddUint64(&c.failedFlushes, 1)
+	atomic.AddInt64(&c.failures, 1)
+	c.debugf("%d entries not delivered: %v", len(batch), err)
+	c.opts.Metrics.Failed(err)
+	c.fallback(entries(batch)...)
+	c.deadLetter(err, batch)
+
+	if c.opts.OnError != nil {
+		c.opts.OnError(err, entries(batch))
+	}
+
+	return err
+}
+
+// entries returns the formatted entries of batch.
+func entries(batch []record) [][]byte {
+	e := make([][]byte, len(batch))
+	for i, rec := range batch {
+		e[i] = rec.data
+	}
+
+	return e
+}
+
+// retryable tells if a request that failed with err is worth trying again.
+func (c *Client) retryable(err error) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+
+	var stepErr *beforeSendError
+	if errors.As(err, &stepErr) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+
+	return true
+}
+
+// encode marshals batch into a JSON array or NDJSON, compressed if needed,
+// or into lines for ProtocolTCP and ProtocolAgent. Uncompressed bodies are
+// made of segments pointing to the entries, to be streamed in the request
+// without copying them.
+// It also returns the size of the uncompressed body. Nothing is encoded
+// when there is a Sink, since it takes the entries as they are.
+func (c *Client) encode(batch []record) (*payload, int, error) {
+	if c.opts.Sink != nil {
+		size := 0
+		for _, rec := range batch {
+			size += len(rec.data)
+		}
+		return nil, size, nil
+	}
+
+	if c.opts.Protocol != ProtocolHTTP {
+		body := newPayload()
+		err := c.encodeLines(body.buffer, batch)
+		return body, body.Len(), err
+	}
+
+	var segments [][]byte
+	var size int
+	if c.opts.BodyFormat != JSONArray {
+		segments, size = lineSegments(batch)
+	} else {
+		segments, size = arraySegments(batch)
+	}
+
+	if !c.opts.Compress {
+		// the entries are not copied, the request body reads them in place
+		return &payload{segments: segments, size: size}, size, nil
+	}
+
+	body := newPayload()
+
+	w := gzipPool.Get().(*gzip.Writer)
+	defer gzipPool.Put(w)
+	w.Reset(body.buffer)
+
+	for _, segment := range segments {
+		_, err := w.Write(segment)
+		if err != nil {
+			return body, 0, err
+		}
+	}
+
+	err := w.Close()
+	if err != nil {
+		return body, 0, err
+	}
+
+	return body, size, nil
+}
+
+// JSON array punctuation, shared by every batch.
+var (
+	openBracket  = []byte("[")
+	comma        = []byte(",")
+	closeBracket = []byte("]")
+)
+
+// arraySegments returns the segments, punctuation and entries, making up
+// batch as a JSON array, and its total size.
+func arraySegments(batch []record) ([][]byte, int) {
+	segments := make([][]byte, 0, 2*len(batch)+1)
+	segments = append(segments, openBracket)
+
+	for i, rec := range batch {
+		// entries after the first one need a comma to separate them from
+		// the previous one
+		if i > 0 {
+			segments = append(segments, comma)
+		}
+
+		segments = append(segments, rec.data)
+	}
+
+	segments = append(segments, closeBracket)
+
+	size := 0
+	for _, segment := range segments {
+		size += len(segment)
+	}
+
+	return segments, size
+}
+
+// newline terminates each entry in NDJSON and PlainText bodies.
+var newline = []byte("\n")
+
+// lineSegments returns the segments, entries and newlines, making up batch
+// as NDJSON or PlainText, and its total size.
+func lineSegments(batch []record) ([][]byte, int) {
+	segments := make([][]byte, 0, 2*len(batch))
+	size := 0
+
+	for _, rec := range batch {
+		segments = append(segments, rec.data)
+		size += len(rec.data)
+
+		if end := lineEnd(rec.data); len(end) > 0 {
+			segments = append(segments, end)
+			size += len(end)
+		}
+	}
+
+	return segments, size
+}
+
+// lineEnd returns the newline terminating data as a line, or nothing if it
+// already ends with one: formatters like logrus.JSONFormatter end entries
+// with a newline, and a second one would make an empty line.
+func lineEnd(data []byte) []byte {
+	if bytes.HasSuffix(data, newline) {
+		return nil
+	}
+
+	return newline
+}
+
+// post makes the HTTP request carrying body.
+func (c *Client) post(body *payload) error {
+	ctx, cancel := context.WithTimeout(c.ctx, c.opts.RequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.postURL, body.body())
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = int64(body.Len())
+	req.GetBody = func() (io.ReadCloser, error) {
+		return body.body(), nil
+	}
+
+	for k, v := range c.opts.Headers {
+		req.Header.Set(k, v)
+	}
+
+	key, err := c.apiKey()
+	if err != nil {
+		return err
+	}
+
+	if key != "" {
+		if c.opts.APIKeyInPath {
+			req.URL.Path = strings.TrimSuffix(req.URL.Path, "/") + "/" + key
+			req.URL.RawPath = ""
+		} else {
+			req.Header.Set("DD-API-KEY", key)
+		}
+	}
+
+	switch c.opts.BodyFormat {
+	case NDJSON:
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	case PlainText:
+		req.Header.Set("Content-Type", "text/plain")
+	default:
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.opts.Compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	if len(c.opts.BeforeSend) > 0 {
+		err = c.beforeSend(req, body)
+		if err != nil {
+			return err
+		}
+	}
+
+	// do request
+	res, err := c.client.Do(req)
+	if err != nil {
+		if c.opts.APIKeyInPath {
+			return redactKey(err, key)
+		}
+		return err
+	}
+	res.Body.Close()
+
+	// the path may hold the API key
+	c.debugf("%s %s: %s", req.Method, req.URL.Host, res.Status)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &StatusError{StatusCode: res.StatusCode}
+	}
+
+	return nil
+}
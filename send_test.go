@@ -0,0 +1,73 @@
+package dogrus_test
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+)
+
+func TestFlushStats(t *testing.T) {
+	entry := `{"message":"` + strings.Repeat("hello ", 50) + `"}`
+	raw := len("[" + entry + "," + entry + "]")
+
+	tests := []struct {
+		name     string
+		compress bool
+		failures int
+	}{
+		{"uncompressed", false, 0},
+		{"compressed", true, 0},
+		{"retried", false, 2},
+		{"compressed and retried", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flushed := make(chan dogrus.FlushStats, 1)
+
+			intake := dogrustest.NewIntake(t, "key")
+			for i := 0; i < tt.failures; i++ {
+				intake.Fail(http.StatusServiceUnavailable)
+			}
+
+			c := dogrus.NewClient("key", dogrus.Opts{
+				PostURL:      intake.URL,
+				Compress:     tt.compress,
+				MaxRetries:   2,
+				RetryBackoff: time.Millisecond,
+				OnFlush:      func(s dogrus.FlushStats) { flushed <- s },
+			})
+			defer c.Close()
+
+			c.Enqueue([]byte(entry))
+			c.Enqueue([]byte(entry))
+			if err := c.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			s := <-flushed
+			if s.Entries != 2 || s.Retries != tt.failures {
+				t.Errorf("Entries %d Retries %d, want 2 and %d", s.Entries, s.Retries, tt.failures)
+			}
+
+			if !tt.compress {
+				if s.Bytes != raw || s.CompressionRatio != 1 {
+					t.Errorf("Bytes %d CompressionRatio %v, want %d and 1", s.Bytes, s.CompressionRatio, raw)
+				}
+				return
+			}
+
+			if s.Bytes >= raw {
+				t.Errorf("Bytes %d, want less than the %d uncompressed", s.Bytes, raw)
+			}
+			if got := float64(s.Bytes) * s.CompressionRatio; math.Abs(got-float64(raw)) > 1e-6 {
+				t.Errorf("CompressionRatio %v doesn't give back the %d uncompressed bytes", s.CompressionRatio, raw)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package dogrus
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FlushOnShutdownSignals closes the hook, delivering the queued entries,
+// when the process receives one of signals, by default SIGINT and SIGTERM.
+// Closing can take up to grace, unless it's zero, see CloseContext. After
+// that the signal is raised again, so the process terminates as it would
+// have done without the hook.
+//
+// Applications handling the signals on their own should call Close from
+// their handler instead, since they'd receive the signal twice.
+// The returned function stops listening for signals.
+func (d *Hook) FlushOnShutdownSignals(grace time.Duration, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+
+	go func() {
+		select {
+		case sig := <-ch:
+			ctx := context.Background()
+			if grace > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, grace)
+				defer cancel()
+			}
+
+			d.CloseContext(ctx)
+			stop()
+
+			p, err := os.FindProcess(os.Getpid())
+			if err == nil {
+				err = p.Signal(sig)
+			}
+			if err != nil {
+				// the signal can't be raised again on this platform
+				os.Exit(1)
+			}
+
+		case <-done:
+		}
+	}()
+
+	return stop
+}
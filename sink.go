@@ -0,0 +1,24 @@
+package dogrus
+
+import "context"
+
+// Sink delivers batches of formatted entries to a backend other than
+// Datadog, such as Loki, Elasticsearch or an internal log gateway, see
+// Opts.Sink.
+//
+// Send is called from the sender goroutine, one batch at a time unless
+// Concurrency is set, with a context bounded by RequestTimeout. The batch
+// must not be retained after Send returns. A failed batch is tried again
+// according to MaxRetries, unless the error is a *StatusError with a 4xx
+// code other than 429.
+type Sink interface {
+	Send(ctx context.Context, batch [][]byte) error
+}
+
+// SinkFunc is an adapter to use a function as a Sink.
+type SinkFunc func(ctx context.Context, batch [][]byte) error
+
+// Send calls f(ctx, batch).
+func (f SinkFunc) Send(ctx context.Context, batch [][]byte) error {
+	return f(ctx, batch)
+}
@@ -0,0 +1,104 @@
+package dogrus_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+)
+
+func TestSinkReceivesEntries(t *testing.T) {
+	var (
+		received []string
+		timeout  time.Duration
+	)
+
+	c := dogrus.NewClient("key", dogrus.Opts{
+		RequestTimeout: 5 * time.Second,
+		Compress:       true,
+		BodyFormat:     dogrus.NDJSON,
+		Sink: dogrus.SinkFunc(func(ctx context.Context, batch [][]byte) error {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Error("no deadline on the context")
+			}
+			timeout = time.Until(deadline)
+
+			for _, e := range batch {
+				received = append(received, string(e))
+			}
+			return nil
+		}),
+	})
+
+	c.Enqueue([]byte(`{"message":"one"}`))
+	c.Enqueue([]byte(`{"message":"two"}`))
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 2 || received[0] != `{"message":"one"}` || received[1] != `{"message":"two"}` {
+		t.Errorf("received %q, want the entries as they were enqueued", received)
+	}
+	if timeout <= 0 || timeout > 5*time.Second {
+		t.Errorf("the deadline is %s away, want within RequestTimeout", timeout)
+	}
+}
+
+func TestSinkErrors(t *testing.T) {
+	unavailable := &dogrus.StatusError{StatusCode: http.StatusServiceUnavailable}
+	badRequest := &dogrus.StatusError{StatusCode: http.StatusBadRequest}
+	closed := errors.New("connection closed")
+
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		reported error
+	}{
+		{"retried until it succeeds", []error{unavailable, closed}, 3, nil},
+		{"retries exhausted", []error{closed, closed, closed}, 3, closed},
+		{"not retryable", []error{badRequest}, 1, badRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				attempts int
+				reported error
+				batch    [][]byte
+			)
+
+			c := dogrus.NewClient("key", dogrus.Opts{
+				MaxRetries:   2,
+				RetryBackoff: time.Millisecond,
+				Sink: dogrus.SinkFunc(func(ctx context.Context, _ [][]byte) error {
+					attempts++
+					if attempts <= len(tt.errs) {
+						return tt.errs[attempts-1]
+					}
+					return nil
+				}),
+				OnError: func(err error, b [][]byte) {
+					reported, batch = err, b
+				},
+			})
+
+			c.Enqueue([]byte(`{"message":"hello"}`))
+			c.Close()
+
+			if attempts != tt.attempts {
+				t.Errorf("Send called %d times, want %d", attempts, tt.attempts)
+			}
+			if reported != tt.reported {
+				t.Errorf("OnError received %v, want %v", reported, tt.reported)
+			}
+			if tt.reported != nil && (len(batch) != 1 || string(batch[0]) != `{"message":"hello"}`) {
+				t.Errorf("OnError received the batch %q", batch)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+// Package slogdd provides a log/slog handler sending records to Datadog
+// through a dogrus client.
+//
+// Records go through the same queue, batching and retry machinery of the
+// dogrus hook, so logrus and slog can be used side by side:
+//
+//	hook := dogrus.New(apiKey, dogrus.Opts{})
+//	logrus.AddHook(hook)
+//	slog.SetDefault(slog.New(slogdd.New(hook.Client(), nil)))
+//
+// Services not using logrus at all can create a client with
+// dogrus.NewClient instead.
+package slogdd
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/Pitasi/dogrus"
+)
+
+// Options configures a Handler.
+type Options struct {
+	// Level is the minimum level of the records sent to Datadog.
+	// By default is slog.LevelInfo.
+	Level slog.Leveler
+
+	// AddSource adds the logger.name, logger.method_name, logger.file and
+	// logger.line attributes describing where the record was logged.
+	AddSource bool
+
+	// ReplaceAttr is called on every attribute, like in
+	// slog.HandlerOptions, after the standard keys are renamed to the
+	// Datadog ones.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+}
+
+// Handler is a slog.Handler formatting records as JSON objects with
+// Datadog standard keys and enqueuing them in a dogrus client.
+type Handler struct {
+	inner slog.Handler
+}
+
+// New creates a Handler sending records through client.
+// The attributes returned by client.Metadata are added to every record.
+func New(client *dogrus.Client, opts *Options) *Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	inner := slog.NewJSONHandler(writer{client}, &slog.HandlerOptions{
+		Level:       opts.Level,
+		AddSource:   opts.AddSource,
+		ReplaceAttr: replaceAttr(opts.ReplaceAttr),
+	})
+
+	metadata := client.Metadata()
+	attrs := make([]slog.Attr, 0, len(metadata))
+	for k, v := range metadata {
+		attrs = append(attrs, slog.String(k, v))
+	}
+
+	return &Handler{inner: inner.WithAttrs(attrs)}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{inner: h.inner.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name)}
+}
+
+// replaceAttr renames the standard slog keys to the ones used by the dogrus
+// formatter, then calls next if not nil.
+func replaceAttr(next func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 {
+			switch a.Key {
+			case slog.TimeKey:
+				a.Key = "timestamp"
+
+			case slog.MessageKey:
+				a.Key = "message"
+
+			case slog.LevelKey:
+				a.Value = slog.StringValue(level(a.Value))
+
+			case slog.SourceKey:
+				if src, ok := a.Value.Any().(*slog.Source); ok {
+					name, method := dogrus.SplitFunction(src.Function)
+					a = slog.Group("logger",
+						slog.String("name", name),
+						slog.String("method_name", method),
+						slog.String("file", src.File),
+						slog.Int("line", src.Line),
+					)
+				}
+			}
+		}
+
+		if next != nil {
+			return next(groups, a)
+		}
+
+		return a
+	}
+}
+
+// level converts a slog level to the names used by logrus, which are
+// understood by Datadog.
+func level(v slog.Value) string {
+	l, ok := v.Any().(slog.Level)
+	if !ok {
+		return strings.ToLower(v.String())
+	}
+
+	switch {
+	case l < slog.LevelInfo:
+		return "debug"
+	case l < slog.LevelWarn:
+		return "info"
+	case l < slog.LevelError:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// writer enqueues every JSON object written by slog.JSONHandler, which
+// makes a single Write call per record.
+type writer struct {
+	client *dogrus.Client
+}
+
+func (w writer) Write(p []byte) (int, error) {
+	// the buffer is reused by slog after Write returns
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	err := w.client.Enqueue(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,87 @@
+package dogrus
+
+// Spool is a persistent buffer where entries are written before being
+// queued, so they aren't lost if the process crashes or exits while Datadog
+// is unreachable. The spool subpackage provides a file-based implementation.
+//
+// Entries are acknowledged once delivered or deliberately discarded.
+// Entries still pending when the client is created are sent again first.
+type Spool interface {
+	// Append stores entry, returning an id used to acknowledge it.
+	Append(entry []byte) (uint64, error)
+
+	// Ack marks the entries with the given ids as not needed anymore.
+	Ack(ids ...uint64) error
+
+	// Pending returns the entries appended and never acknowledged, with
+	// their ids, in the order they were appended.
+	Pending() ([]uint64, [][]byte, error)
+}
+
+// spool writes entry to the configured Spool, if any, and returns it as a
+// record ready to be queued.
+// If the spool can't store the entry, it's reported to OnError and queued
+// anyway: it can still be delivered, it just won't survive a crash.
+func (c *Client) spool(entry []byte) record {
+	rec := record{data: entry}
+	if c.opts.Spool == nil {
+		return rec
+	}
+
+	id, err := c.opts.Spool.Append(entry)
+	if err != nil {
+		c.reject(err, entry)
+		return rec
+	}
+
+	rec.spoolID = id
+
+	return rec
+}
+
+// unspool acknowledges the records of batch, once they are not needed
+// anymore.
+func (c *Client) unspool(batch []record) {
+	if c.opts.Spool == nil {
+		return
+	}
+
+	ids := make([]uint64, 0, len(batch))
+	for _, rec := range batch {
+		if rec.spoolID != 0 {
+			ids = append(ids, rec.spoolID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return
+	}
+
+	err := c.opts.Spool.Ack(ids...)
+	if err != nil && c.opts.OnError != nil {
+		c.opts.OnError(err, nil)
+	}
+}
+
+// recovered returns the entries left pending in the Spool by a previous
+// run.
+func (c *Client) recovered() []record {
+	if c.opts.Spool == nil {
+		return nil
+	}
+
+	ids, entries, err := c.opts.Spool.Pending()
+	if err != nil {
+		if c.opts.OnError != nil {
+			c.opts.OnError(err, nil)
+		}
+		return nil
+	}
+
+	batch := make([]record, len(entries))
+	for i := range entries {
+		batch[i] = record{data: entries[i], spoolID: ids[i]}
+	}
+
+	return batch
+}
@@ -0,0 +1,381 @@
+// Package spool implements a disk-backed write-ahead buffer for log entries.
+//
+// Entries are appended to a single file before being sent, and marked as
+// delivered once Datadog accepted them. If the process crashes, or Datadog is
+// unreachable until it exits, the entries still pending are found in the
+// file the next time it's opened and can be sent again.
+//
+// A Spool can be plugged into a dogrus hook using Opts.Spool:
+//
+//	sp, err := spool.Open("/var/lib/myapp/logs.spool", spool.Opts{})
+//	if err != nil {
+//		// ...
+//	}
+//	defer sp.Close()
+//	hook := dogrus.New(apiKey, dogrus.Opts{Spool: sp})
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ErrFull is returned by Append when the spool file reached Opts.MaxBytes.
+var ErrFull = errors.New("spool: full")
+
+// ErrClosed is returned when using a Spool that has been closed.
+var ErrClosed = errors.New("spool: closed")
+
+// Each record in the file is made of a header followed by the entry:
+//
+//	state    1 byte, statePending or stateAcked
+//	id       8 bytes, big endian
+//	length   4 bytes, big endian, length of the entry
+//	checksum 4 bytes, big endian, CRC-32 of the entry
+//
+// Acknowledging an entry only rewrites its state byte, the file is compacted
+// later when enough space can be reclaimed.
+const headerSize = 1 + 8 + 4 + 4
+
+const (
+	statePending byte = 0
+	stateAcked   byte = 1
+)
+
+// Opts are variables for tuning the spool.
+// All options can be left empty and they will be filled with default values.
+type Opts struct {
+	// MaxBytes caps the size of the spool file. Appending entries after
+	// that fails with ErrFull until some of them are acknowledged.
+	// By default is 100MB.
+	MaxBytes int64
+
+	// Sync makes every Append wait for the entry to be written to stable
+	// storage. Without it, entries survive a crash of the process but not of
+	// the whole machine.
+	Sync bool
+}
+
+// record tells where a pending entry is in the file.
+type record struct {
+	offset int64
+	size   int64
+}
+
+// Spool is a file of log entries waiting to be delivered.
+// It's safe for concurrent use.
+type Spool struct {
+	mu         sync.Mutex
+	path       string
+	opts       Opts
+	f          *os.File
+	size       int64
+	ackedBytes int64
+	nextID     uint64
+	pending    map[uint64]record
+}
+
+// Open opens the spool file at path, creating it if it doesn't exist.
+// Entries left pending by a previous run are available through Pending.
+func Open(path string, opts Opts) (*Spool, error) {
+	if opts.MaxBytes == 0 {
+		opts.MaxBytes = 100 * 1024 * 1024
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Spool{
+		path:    path,
+		opts:    opts,
+		f:       f,
+		nextID:  1,
+		pending: make(map[uint64]record),
+	}
+
+	err = s.load()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// load reads the records in the file. A record that was only partially
+// written, because of a crash, is discarded along with anything after it,
+// and so is a record whose length can't be right, being larger than
+// MaxBytes or than the rest of the file.
+func (s *Spool) load() error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := info.Size()
+
+	r := bufio.NewReader(io.NewSectionReader(s.f, 0, fileSize))
+	header := make([]byte, headerSize)
+
+	var offset int64
+	for {
+		_, err := io.ReadFull(r, header)
+		if err != nil {
+			break
+		}
+
+		id := binary.BigEndian.Uint64(header[1:9])
+		length := int64(binary.BigEndian.Uint32(header[9:13]))
+		checksum := binary.BigEndian.Uint32(header[13:17])
+
+		size := headerSize + length
+		if length > s.opts.MaxBytes || offset+size > fileSize {
+			break
+		}
+
+		entry := make([]byte, length)
+		_, err = io.ReadFull(r, entry)
+		if err != nil || crc32.ChecksumIEEE(entry) != checksum {
+			break
+		}
+
+		if header[0] == statePending {
+			s.pending[id] = record{offset: offset, size: size}
+		} else {
+			s.ackedBytes += size
+		}
+
+		if id >= s.nextID {
+			s.nextID = id + 1
+		}
+
+		offset += size
+	}
+
+	s.size = offset
+
+	return s.f.Truncate(offset)
+}
+
+// Append writes entry at the end of the spool file and returns the id to be
+// used to acknowledge it.
+func (s *Spool) Append(entry []byte) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return 0, ErrClosed
+	}
+
+	size := int64(headerSize + len(entry))
+
+	if s.size+size > s.opts.MaxBytes && s.ackedBytes > 0 {
+		err := s.compact()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if s.size+size > s.opts.MaxBytes {
+		return 0, ErrFull
+	}
+
+	id := s.nextID
+
+	buf := make([]byte, size)
+	buf[0] = statePending
+	binary.BigEndian.PutUint64(buf[1:9], id)
+	binary.BigEndian.PutUint32(buf[9:13], uint32(len(entry)))
+	binary.BigEndian.PutUint32(buf[13:17], crc32.ChecksumIEEE(entry))
+	copy(buf[headerSize:], entry)
+
+	_, err := s.f.WriteAt(buf, s.size)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.opts.Sync {
+		err = s.f.Sync()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	s.pending[id] = record{offset: s.size, size: size}
+	s.size += size
+	s.nextID++
+
+	return id, nil
+}
+
+// Ack marks the entries with the given ids as delivered, so they won't be
+// returned by Pending anymore. Unknown ids are ignored.
+// The file is compacted once most of it is taken by acknowledged entries.
+func (s *Spool) Ack(ids ...uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return ErrClosed
+	}
+
+	acked := []byte{stateAcked}
+
+	for _, id := range ids {
+		rec, ok := s.pending[id]
+		if !ok {
+			continue
+		}
+
+		_, err := s.f.WriteAt(acked, rec.offset)
+		if err != nil {
+			return err
+		}
+
+		delete(s.pending, id)
+		s.ackedBytes += rec.size
+	}
+
+	if len(s.pending) == 0 {
+		// nothing left to keep, start over with an empty file
+		err := s.f.Truncate(0)
+		if err != nil {
+			return err
+		}
+
+		s.size = 0
+		s.ackedBytes = 0
+
+		return nil
+	}
+
+	if s.ackedBytes > s.size/2 {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// Pending returns the entries that haven't been acknowledged yet, in the
+// order they were appended, along with their ids.
+func (s *Spool) Pending() ([]uint64, [][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil, nil, ErrClosed
+	}
+
+	ids := s.sortedIDs()
+	entries := make([][]byte, 0, len(ids))
+
+	for _, id := range ids {
+		rec := s.pending[id]
+
+		entry := make([]byte, rec.size-headerSize)
+		_, err := s.f.ReadAt(entry, rec.offset+headerSize)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return ids, entries, nil
+}
+
+// Len returns the number of entries that haven't been acknowledged yet.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pending)
+}
+
+// Close closes the spool file. Pending entries are kept for the next Open.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return ErrClosed
+	}
+
+	err := s.f.Close()
+	s.f = nil
+
+	return err
+}
+
+// sortedIDs returns the ids of pending entries in the order they appear in
+// the file.
+func (s *Spool) sortedIDs() []uint64 {
+	ids := make([]uint64, 0, len(s.pending))
+	for id := range s.pending {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return s.pending[ids[i]].offset < s.pending[ids[j]].offset
+	})
+
+	return ids
+}
+
+// compact rewrites the spool file keeping only the pending entries.
+// The new file is written aside and renamed over the old one, so a crash
+// during compaction leaves one of the two intact.
+func (s *Spool) compact() error {
+	tmpPath := s.path + ".tmp"
+
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[uint64]record, len(s.pending))
+
+	var offset int64
+	for _, id := range s.sortedIDs() {
+		rec := s.pending[id]
+
+		buf := make([]byte, rec.size)
+		_, err = s.f.ReadAt(buf, rec.offset)
+		if err == nil {
+			_, err = tmp.WriteAt(buf, offset)
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return err
+		}
+
+		pending[id] = record{offset: offset, size: rec.size}
+		offset += rec.size
+	}
+
+	err = tmp.Sync()
+	if err == nil {
+		err = os.Rename(tmpPath, s.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	s.f.Close()
+	s.f = tmp
+	s.pending = pending
+	s.size = offset
+	s.ackedBytes = 0
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package spool
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// corrupt appends to the spool file at path a header claiming an entry of
+// length bytes, followed by n bytes.
+func corrupt(t *testing.T, path string, length uint32, n int) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, headerSize+n)
+	binary.BigEndian.PutUint64(buf[1:9], 99)
+	binary.BigEndian.PutUint32(buf[9:13], length)
+
+	if _, err := f.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenRejectsImpossibleLengths(t *testing.T) {
+	tests := []struct {
+		name   string
+		length uint32
+		n      int
+	}{
+		{"beyond the file", 1<<32 - 1, 10},
+		{"beyond MaxBytes", 200, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "logs.spool")
+			opts := Opts{MaxBytes: 100}
+
+			s, err := Open(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Append([]byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			s.Close()
+
+			corrupt(t, path, tt.length, tt.n)
+
+			s, err = Open(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			_, entries, err := s.Pending()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || string(entries[0]) != "hello" {
+				t.Fatalf("pending entries are %q, want hello only", entries)
+			}
+
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != headerSize+5 {
+				t.Fatalf("file is %d bytes, the corrupt record has not been truncated", info.Size())
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package dogrus_test
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+	"github.com/Pitasi/dogrus/spool"
+)
+
+func openSpool(t *testing.T, path string) *spool.Spool {
+	t.Helper()
+
+	sp, err := spool.Open(path, spool.Opts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sp
+}
+
+func TestSpoolRecoveredAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.spool")
+	intake := dogrustest.NewIntake(t, "key")
+
+	// first run: the intake is down until the client is closed
+	sp := openSpool(t, path)
+	intake.Fail(http.StatusServiceUnavailable)
+
+	c := dogrus.NewClient("key", dogrus.Opts{PostURL: intake.URL, Spool: sp})
+	for _, msg := range []string{"one", "two", "three"} {
+		c.Enqueue([]byte(`{"message":"` + msg + `"}`))
+	}
+	if err := c.Close(); err == nil {
+		t.Fatal("the intake accepted the entries")
+	}
+	sp.Close()
+
+	// second run: the entries pending in the spool are sent first
+	sp = openSpool(t, path)
+	if sp.Len() != 3 {
+		t.Fatalf("%d entries in the spool, want 3", sp.Len())
+	}
+
+	c = dogrus.NewClient("key", dogrus.Opts{PostURL: intake.URL, Spool: sp})
+	c.Enqueue([]byte(`{"message":"four"}`))
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := intake.WaitForEntries(t, 4, 5*time.Second)
+	if len(entries) != 4 {
+		t.Fatalf("received %d entries, want each one once", len(entries))
+	}
+	for i, msg := range []string{"one", "two", "three", "four"} {
+		if entries[i]["message"] != msg {
+			t.Errorf("entry %d is %v, want %s", i, entries[i]["message"], msg)
+		}
+	}
+
+	if sp.Len() != 0 {
+		t.Errorf("%d entries still in the spool after delivery", sp.Len())
+	}
+	sp.Close()
+
+	// third run: nothing is sent again
+	sp = openSpool(t, path)
+	defer sp.Close()
+
+	if sp.Len() != 0 {
+		t.Fatalf("%d delivered entries found in the spool after reopening", sp.Len())
+	}
+}
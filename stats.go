@@ -0,0 +1,132 @@
+package dogrus
+
+import (
+	"expvar"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are cumulative counters describing the activity of a Hook, or of a
+// Client, since its creation.
+type Stats struct {
+	// Enqueued is the number of entries accepted in the queue.
+	Enqueued uint64
+
+	// Sent is the number of entries successfully delivered to Datadog.
+	Sent uint64
+
+	// Dropped is the number of entries discarded because the queue was
+	// full, or because they were larger than MaxEntryBytes.
+	Dropped uint64
+
+	// SampledOut is the number of entries left out by SampleRates and
+	// AdaptiveSampling.
+	SampledOut uint64
+
+	// AdaptiveSampleRate is the sample rate currently applied by
+	// AdaptiveSampling, 1 if it's not enabled.
+	AdaptiveSampleRate float64
+
+	// FailedFlushes is the number of batches that couldn't be delivered.
+	FailedFlushes uint64
+
+	// Retries is the number of requests tried again after a failure.
+	Retries uint64
+
+	// QueueDepth is the number of entries currently waiting in the queue.
+	QueueDepth int
+
+	// QueuedBytes is the serialized size of the entries currently waiting in
+	// the queue.
+	QueuedBytes int64
+
+	// HeldBytes is the serialized size of the entries currently held in
+	// memory, counted against MaxQueuedBytes: queued, batched, being sent
+	// or retried.
+	HeldBytes int64
+
+	// Circuit is the current state of the circuit breaker.
+	Circuit CircuitState
+
+	// LastFlush is the time of the last successful delivery, zero if there
+	// wasn't any.
+	LastFlush time.Time
+}
+
+// Stats returns a snapshot of the client counters.
+// It's safe to call it concurrently with the client being used.
+func (c *Client) Stats() Stats {
+	s := Stats{
+		Enqueued:           atomic.LoadUint64(&c.enqueued),
+		Sent:               atomic.LoadUint64(&c.sent),
+		Dropped:            atomic.LoadUint64(&c.dropped),
+		FailedFlushes:      atomic.LoadUint64(&c.failedFlushes),
+		Retries:            atomic.LoadUint64(&c.retries),
+		QueueDepth:         len(c.queue),
+		QueuedBytes:        atomic.LoadInt64(&c.queuedBytes),
+		HeldBytes:          atomic.LoadInt64(&c.heldBytes),
+		Circuit:            c.breaker.State(),
+		AdaptiveSampleRate: 1,
+	}
+
+	if last := atomic.LoadInt64(&c.lastFlush); last != 0 {
+		s.LastFlush = time.Unix(0, last)
+	}
+
+	return s
+}
+
+// Stats returns a snapshot of the hook counters, including the ones of its
+// Client and of the clients of Router destinations, but not of Mirrors.
+// Circuit is the state of the default destination.
+// It's safe to call it concurrently with the hook being used.
+func (d *Hook) Stats() Stats {
+	s := d.client.Stats()
+	for _, c := range d.routed() {
+		s.add(c.Stats())
+	}
+
+	s.SampledOut = atomic.LoadUint64(&d.sampledOut)
+
+	if d.adaptive != nil {
+		s.AdaptiveSampleRate = d.adaptive.current()
+	}
+
+	return s
+}
+
+// add sums the counters of o to s.
+func (s *Stats) add(o Stats) {
+	s.Enqueued += o.Enqueued
+	s.Sent += o.Sent
+	s.Dropped += o.Dropped
+	s.FailedFlushes += o.FailedFlushes
+	s.Retries += o.Retries
+	s.QueueDepth += o.QueueDepth
+	s.QueuedBytes += o.QueuedBytes
+	s.HeldBytes += o.HeldBytes
+
+	if o.LastFlush.After(s.LastFlush) {
+		s.LastFlush = o.LastFlush
+	}
+}
+
+// PublishExpvar exports the client Stats as an expvar variable with the
+// given name, so they are served by the /debug/vars handler.
+// Like expvar.Publish, it panics if name is already in use.
+func (c *Client) PublishExpvar(name string) {
+	publishExpvar(name, c.Stats)
+}
+
+// PublishExpvar exports the hook Stats as an expvar variable with the given
+// name, so they are served by the /debug/vars handler.
+// Like expvar.Publish, it panics if name is already in use.
+func (d *Hook) PublishExpvar(name string) {
+	publishExpvar(name, d.Stats)
+}
+
+func publishExpvar(name string, stats func() Stats) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return stats()
+	}))
+}
@@ -0,0 +1,27 @@
+package dogrus
+
+import "github.com/sirupsen/logrus"
+
+// StatusKey is the Datadog reserved attribute holding the severity of an
+// entry. It takes precedence over "level" in the status remapper.
+const StatusKey = "status"
+
+// DatadogStatuses maps logrus levels to the values recognised by the
+// Datadog status remapper.
+var DatadogStatuses = map[logrus.Level]string{
+	logrus.PanicLevel: "emergency",
+	logrus.FatalLevel: "critical",
+	logrus.ErrorLevel: "error",
+	logrus.WarnLevel:  "warning",
+	logrus.InfoLevel:  "info",
+	logrus.DebugLevel: "debug",
+	logrus.TraceLevel: "debug",
+}
+
+// addStatus adds the Datadog status of e, unless the entry already has one
+// or its level isn't mapped.
+func (d *Hook) addStatus(e *logrus.Entry) {
+	if status, ok := d.opts.Statuses[e.Level]; ok {
+		setDefault(e, StatusKey, status)
+	}
+}
@@ -0,0 +1,201 @@
+// Package stdlogdd sends the output of the standard library log package to
+// Datadog through a dogrus client.
+//
+// The header written by log, made of prefix, date, time and file, is parsed
+// into the Datadog standard attributes, so legacy code still using log ends
+// up in Datadog like the rest:
+//
+//	client := dogrus.NewClient(apiKey, dogrus.Opts{})
+//	log.SetOutput(stdlogdd.NewWriter(client, &stdlogdd.Options{Flags: log.Flags()}))
+//
+// or with a dedicated logger:
+//
+//	logger := stdlogdd.New(client, &stdlogdd.Options{Prefix: "legacy: ", Flags: log.LstdFlags})
+package stdlogdd
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+)
+
+// Options configures a Writer.
+type Options struct {
+	// Level is the level of the entries, since log doesn't have any.
+	// By default is info.
+	Level string
+
+	// Prefix and Flags must be the ones of the logger writing to the
+	// Writer, see log.SetPrefix and log.SetFlags. They are used to parse
+	// the header of each line. The prefix, if any, is sent as the
+	// logger.name attribute, without the trailing colon.
+	Prefix string
+	Flags  int
+}
+
+// Writer is an io.Writer turning every message written by a log.Logger into
+// a log entry enqueued in a dogrus client.
+// Lines whose header can't be parsed are sent as they are, timestamped with
+// the current time of the client Clock.
+type Writer struct {
+	client   *dogrus.Client
+	opts     Options
+	metadata map[string]string
+}
+
+// NewWriter creates a Writer sending entries through client.
+// The attributes returned by client.Metadata are added to every entry.
+func NewWriter(client *dogrus.Client, opts *Options) *Writer {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	w := &Writer{
+		client:   client,
+		opts:     *opts,
+		metadata: client.Metadata(),
+	}
+
+	if w.opts.Level == "" {
+		w.opts.Level = "info"
+	}
+
+	return w
+}
+
+// New creates a log.Logger writing to a Writer, with the prefix and flags
+// of opts.
+func New(client *dogrus.Client, opts *Options) *log.Logger {
+	w := NewWriter(client, opts)
+	return log.New(w, w.opts.Prefix, w.opts.Flags)
+}
+
+// Write implements io.Writer. A log.Logger writes a single message per
+// call, so messages spanning multiple lines are kept in a single entry.
+func (w *Writer) Write(p []byte) (int, error) {
+	line := string(bytes.TrimRight(p, "\r\n"))
+	if strings.TrimSpace(line) == "" {
+		return len(p), nil
+	}
+
+	entry := make(map[string]interface{}, len(w.metadata)+6)
+	for k, v := range w.metadata {
+		entry[k] = v
+	}
+	entry["level"] = w.opts.Level
+
+	t, message, ok := w.parse(line, entry)
+	if !ok {
+		// drop whatever was parsed so far
+		delete(entry, dogrus.LoggerNameKey)
+		delete(entry, dogrus.LoggerFileKey)
+		delete(entry, dogrus.LoggerLineKey)
+		t, message = w.client.Clock().Now(), line
+	}
+
+	entry["timestamp"] = t.Format(time.RFC3339Nano)
+	entry["message"] = message
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	err = w.client.Enqueue(data)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// parse splits line into the header written by log, adding its attributes
+// to entry, and the message. It follows the format of log.Logger.Output.
+func (w *Writer) parse(line string, entry map[string]interface{}) (time.Time, string, bool) {
+	flags := w.opts.Flags
+	prefix := w.opts.Prefix
+
+	if name := strings.TrimRight(strings.TrimSpace(prefix), ":"); name != "" {
+		entry[dogrus.LoggerNameKey] = name
+	}
+
+	if flags&log.Lmsgprefix == 0 {
+		if !strings.HasPrefix(line, prefix) {
+			return time.Time{}, "", false
+		}
+		line = line[len(prefix):]
+	}
+
+	now := w.client.Clock().Now()
+
+	t := now
+	if flags&(log.Ldate|log.Ltime|log.Lmicroseconds) != 0 {
+		var layout string
+		if flags&log.Ldate != 0 {
+			layout = "2006/01/02 "
+		}
+		if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+			layout += "15:04:05"
+			if flags&log.Lmicroseconds != 0 {
+				layout += ".000000"
+			}
+			layout += " "
+		}
+
+		if len(line) < len(layout) {
+			return time.Time{}, "", false
+		}
+
+		loc := time.Local
+		if flags&log.LUTC != 0 {
+			loc = time.UTC
+		}
+
+		parsed, err := time.ParseInLocation(layout, line[:len(layout)], loc)
+		if err != nil {
+			return time.Time{}, "", false
+		}
+		line = line[len(layout):]
+
+		if flags&log.Ldate == 0 {
+			// only the time of day is known, assume it's today
+			now := now.In(loc)
+			parsed = time.Date(now.Year(), now.Month(), now.Day(),
+				parsed.Hour(), parsed.Minute(), parsed.Second(), parsed.Nanosecond(), loc)
+		}
+		t = parsed
+	}
+
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		// file:line: message
+		end := strings.Index(line, ": ")
+		if end < 0 {
+			return time.Time{}, "", false
+		}
+
+		colon := strings.LastIndex(line[:end], ":")
+		if colon < 0 {
+			return time.Time{}, "", false
+		}
+
+		n, err := strconv.Atoi(line[colon+1 : end])
+		if err != nil {
+			return time.Time{}, "", false
+		}
+
+		entry[dogrus.LoggerFileKey] = line[:colon]
+		entry[dogrus.LoggerLineKey] = n
+		line = line[end+2:]
+	}
+
+	if flags&log.Lmsgprefix != 0 {
+		line = strings.TrimPrefix(line, prefix)
+	}
+
+	return t, line, true
+}
@@ -0,0 +1,127 @@
+package stdlogdd
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/Pitasi/dogrus/dogrustest"
+)
+
+func TestWriterHeaders(t *testing.T) {
+	now := time.Date(2026, 10, 14, 18, 30, 0, 0, time.UTC)
+	local := func(h, m, s, us int) time.Time {
+		return time.Date(2026, 10, 14, h, m, s, us*1000, time.Local)
+	}
+	today := now.In(time.Local)
+
+	tests := []struct {
+		name    string
+		prefix  string
+		flags   int
+		line    string
+		time    time.Time
+		message string
+		logger  interface{}
+		file    interface{}
+		lineNo  interface{}
+	}{
+		{"no header", "", 0, "hello", now, "hello", nil, nil, nil},
+		{"date and time", "", log.LstdFlags, "2026/10/14 18:12:58 hello", local(18, 12, 58, 0), "hello", nil, nil, nil},
+		{"date", "", log.Ldate, "2026/10/14 hello", local(0, 0, 0, 0), "hello", nil, nil, nil},
+		{"time", "", log.Ltime, "18:12:58 hello",
+			time.Date(today.Year(), today.Month(), today.Day(), 18, 12, 58, 0, time.Local), "hello", nil, nil, nil},
+		{"microseconds", "", log.Ldate | log.Lmicroseconds, "2026/10/14 18:12:58.123456 hello", local(18, 12, 58, 123456), "hello", nil, nil, nil},
+		{"UTC", "", log.LstdFlags | log.Lmicroseconds | log.LUTC, "2026/10/14 18:12:58.000001 hello",
+			time.Date(2026, 10, 14, 18, 12, 58, 1000, time.UTC), "hello", nil, nil, nil},
+		{"short file", "", log.Lshortfile, "main.go:42: key: value", now, "key: value", nil, "main.go", float64(42)},
+		{"long file", "", log.LstdFlags | log.Llongfile, "2026/10/14 18:12:58 /src/app/main.go:7: hello",
+			local(18, 12, 58, 0), "hello", nil, "/src/app/main.go", float64(7)},
+		{"prefix", "legacy: ", log.LstdFlags, "legacy: 2026/10/14 18:12:58 hello", local(18, 12, 58, 0), "hello", "legacy", nil, nil},
+		{"message prefix", "legacy: ", log.LstdFlags | log.Lmsgprefix | log.Lshortfile, "2026/10/14 18:12:58 main.go:1: legacy: hello",
+			local(18, 12, 58, 0), "hello", "legacy", "main.go", float64(1)},
+		{"multiple lines", "", log.Lshortfile, "main.go:3: first\nsecond\n", now, "first\nsecond", nil, "main.go", float64(3)},
+
+		{"unparsable time", "", log.LstdFlags, "not a header at all", now, "not a header at all", nil, nil, nil},
+		{"short line", "", log.LstdFlags, "hello", now, "hello", nil, nil, nil},
+		{"other prefix", "app: ", log.LstdFlags, "other: 2026/10/14 18:12:58 hello", now, "other: 2026/10/14 18:12:58 hello", nil, nil, nil},
+		{"file without line", "", log.Lshortfile, "main.go: hello", now, "main.go: hello", nil, nil, nil},
+		{"file with bad line", "app: ", log.Lshortfile, "app: main.go:x: hello", now, "app: main.go:x: hello", nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := dogrus.NewMemorySink()
+			client := dogrus.NewClient("key", dogrus.Opts{Sink: sink, Clock: dogrustest.NewClock(now)})
+
+			w := NewWriter(client, &Options{Prefix: tt.prefix, Flags: tt.flags})
+			if n, err := w.Write([]byte(tt.line)); err != nil || n != len(tt.line) {
+				t.Fatalf("Write returned %d, %v", n, err)
+			}
+			if err := client.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := sink.Decoded()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			e := entries[0]
+
+			if e["timestamp"] != tt.time.Format(time.RFC3339Nano) {
+				t.Errorf("timestamp is %v, want %s", e["timestamp"], tt.time.Format(time.RFC3339Nano))
+			}
+			if e["message"] != tt.message || e["level"] != "info" {
+				t.Errorf("message is %q at %v, want %q at info", e["message"], e["level"], tt.message)
+			}
+			if e[dogrus.LoggerNameKey] != tt.logger {
+				t.Errorf("%s is %v, want %v", dogrus.LoggerNameKey, e[dogrus.LoggerNameKey], tt.logger)
+			}
+			if e[dogrus.LoggerFileKey] != tt.file || e[dogrus.LoggerLineKey] != tt.lineNo {
+				t.Errorf("caller is %v:%v, want %v:%v", e[dogrus.LoggerFileKey], e[dogrus.LoggerLineKey], tt.file, tt.lineNo)
+			}
+		})
+	}
+}
+
+func TestLoggerOutput(t *testing.T) {
+	sink := dogrus.NewMemorySink()
+	client := dogrus.NewClient("key", dogrus.Opts{Sink: sink})
+
+	logger := New(client, &Options{
+		Level:  "warn",
+		Prefix: "legacy: ",
+		Flags:  log.LstdFlags | log.Lmicroseconds | log.Lshortfile | log.Lmsgprefix,
+	})
+	logger.Print("disk almost full")
+
+	// blank writes are ignored
+	NewWriter(client, nil).Write([]byte("\n"))
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := sink.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	e := entries[0]
+	if e["message"] != "disk almost full" || e["level"] != "warn" || e[dogrus.LoggerNameKey] != "legacy" {
+		t.Errorf("entry is %v", e)
+	}
+	if e[dogrus.LoggerFileKey] != "stdlogdd_test.go" {
+		t.Errorf("%s is %v", dogrus.LoggerFileKey, e[dogrus.LoggerFileKey])
+	}
+	if _, err := time.Parse(time.RFC3339Nano, e["timestamp"].(string)); err != nil {
+		t.Error(err)
+	}
+}
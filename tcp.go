@@ -0,0 +1,139 @@
+package dogrus
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// Protocol is the protocol used to deliver entries to Datadog.
+type Protocol int
+
+const (
+	// ProtocolHTTP posts batches to PostURL, framed as set by BodyFormat.
+	ProtocolHTTP Protocol = iota
+
+	// ProtocolTCP writes entries over a TLS connection to TCPAddr, one per
+	// line, prefixed by the API key. It's meant for networks where HTTP
+	// egress is restricted: Datadog doesn't acknowledge entries sent this
+	// way, so the ones written just before a connection drops can be lost.
+	// Compress and ProxyURL are ignored.
+	ProtocolTCP
+
+	// ProtocolAgent writes entries to a local Datadog Agent listening on
+	// TCPAddr, configured with a tcp logs source, one per line. The
+	// connection is not encrypted and the API key is not sent, it's the
+	// agent that holds it. The same caveats of ProtocolTCP apply.
+	ProtocolAgent
+)
+
+// agentAddr is where the Datadog Agent is usually configured to listen for
+// logs sent over TCP.
+const agentAddr = "localhost:10518"
+
+// tcpIntake returns the address of the Datadog TCP intake of site.
+func tcpIntake(site string) string {
+	if site == "datadoghq.com" {
+		return "intake.logs.datadoghq.com:10516"
+	}
+
+	return "tcp-intake.logs." + site + ":443"
+}
+
+// encodeLines frames batch for the TCP intake into buffer: each entry on
+// its own line, prefixed by the API key unless it's sent to the agent.
+func (c *Client) encodeLines(buffer *bytes.Buffer, batch []record) error {
+	var key string
+	if c.opts.Protocol != ProtocolAgent {
+		var err error
+		key, err = c.apiKey()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, rec := range batch {
+		if key != "" {
+			buffer.WriteString(key)
+			buffer.WriteByte(' ')
+		}
+		buffer.Write(rec.data)
+		buffer.Write(lineEnd(rec.data))
+	}
+
+	return nil
+}
+
+// transmit sends batch, encoded into body, using the configured Sink or
+// Protocol.
+func (c *Client) transmit(batch []record, body *payload) error {
+	if c.opts.Sink != nil {
+		ctx, cancel := context.WithTimeout(c.ctx, c.opts.RequestTimeout)
+		defer cancel()
+
+		return c.opts.Sink.Send(ctx, entries(batch))
+	}
+
+	if c.opts.Protocol != ProtocolHTTP {
+		return c.write(body.Bytes())
+	}
+
+	return c.post(body)
+}
+
+// write sends body over the TCP connection, dialing it if needed.
+// The connection is dropped after any error, so the next attempt opens a
+// new one. With Concurrency, workers take turns on the connection.
+func (c *Client) write(body []byte) error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	if c.conn == nil {
+		conn, err := c.dial()
+		if err != nil {
+			return err
+		}
+
+		c.conn = conn
+	}
+
+	err := c.conn.SetWriteDeadline(time.Now().Add(c.opts.RequestTimeout))
+	if err == nil {
+		_, err = c.conn.Write(body)
+	}
+	if err != nil {
+		c.closeConn()
+		return err
+	}
+
+	return nil
+}
+
+// dial opens a connection to TCPAddr, encrypted unless it's to the agent,
+// which can also be reached through UnixSocketPath.
+func (c *Client) dial() (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, c.opts.RequestTimeout)
+	defer cancel()
+
+	if c.opts.Protocol == ProtocolAgent {
+		dialer := &net.Dialer{}
+		if c.opts.UnixSocketPath != "" {
+			return dialer.DialContext(ctx, "unix", c.opts.UnixSocketPath)
+		}
+		return dialer.DialContext(ctx, "tcp", c.opts.TCPAddr)
+	}
+
+	dialer := &tls.Dialer{Config: c.opts.TLSConfig}
+	return dialer.DialContext(ctx, "tcp", c.opts.TCPAddr)
+}
+
+// closeConn closes the TCP connection, if any. The caller must hold connMu,
+// or be sure the client is done sending.
+func (c *Client) closeConn() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
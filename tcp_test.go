@@ -0,0 +1,34 @@
+package dogrus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeLines(t *testing.T) {
+	batch := []record{
+		{data: []byte(`{"a":1}`)},
+		{data: []byte(`{"a":2}` + "\n")},
+	}
+
+	tests := []struct {
+		protocol Protocol
+		want     string
+	}{
+		{ProtocolTCP, "key {\"a\":1}\nkey {\"a\":2}\n"},
+		{ProtocolAgent, "{\"a\":1}\n{\"a\":2}\n"},
+	}
+
+	for _, tt := range tests {
+		c := &Client{key: "key", opts: Opts{Protocol: tt.protocol}}
+
+		var buf bytes.Buffer
+		if err := c.encodeLines(&buf, batch); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.String(); got != tt.want {
+			t.Errorf("protocol %d: encoded %q, want %q", tt.protocol, got, tt.want)
+		}
+	}
+}
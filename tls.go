@@ -0,0 +1,46 @@
+package dogrus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+// LoadTLSConfig builds a TLSConfig from PEM files. caFile, if not empty,
+// holds the certificates of the CAs trusted in addition to the system ones,
+// such as the one of a TLS-intercepting gateway. certFile and keyFile, if
+// not empty, hold the client certificate presented to relays requiring
+// mutual TLS.
+func LoadTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("dogrus: no certificate found in " + caFile)
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
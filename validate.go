@@ -0,0 +1,143 @@
+package dogrus
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidOpts is wrapped by the errors returned by Opts.Validate.
+var ErrInvalidOpts = errors.New("dogrus: invalid options")
+
+// Validate tells if opts make sense, before the defaults are filled in,
+// returning an error wrapping ErrInvalidOpts that lists every problem
+// found, such as negative durations or a malformed PostURL.
+// New doesn't validate its options, see NewWithError.
+func (opts Opts) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"FlushPeriod", opts.FlushPeriod},
+		{"DedupWindow", opts.DedupWindow},
+		{"RequestTimeout", opts.RequestTimeout},
+		{"IdleConnTimeout", opts.IdleConnTimeout},
+		{"RetryBackoff", opts.RetryBackoff},
+		{"RetryFor", opts.RetryFor},
+		{"BreakerCooldown", opts.BreakerCooldown},
+	}
+	for _, d := range durations {
+		check(d.value >= 0, "%s is negative: %s", d.name, d.value)
+	}
+
+	sizes := []struct {
+		name  string
+		value int
+	}{
+		{"MaxBatchSize", opts.MaxBatchSize},
+		{"MaxBatchBytes", opts.MaxBatchBytes},
+		{"MaxEntriesPerRequest", opts.MaxEntriesPerRequest},
+		{"MaxEntryBytes", opts.MaxEntryBytes},
+		{"QueueSize", opts.QueueSize},
+		{"MaxQueuedBytes", opts.MaxQueuedBytes},
+		{"MaxIdleConnsPerHost", opts.MaxIdleConnsPerHost},
+		{"Concurrency", opts.Concurrency},
+		{"MaxRetries", opts.MaxRetries},
+		{"MaxRetryBytes", opts.MaxRetryBytes},
+		{"MaxDeadLetters", opts.MaxDeadLetters},
+	}
+	for _, n := range sizes {
+		check(n.value >= 0, "%s is negative: %d", n.name, n.value)
+	}
+
+	check(opts.FlushJitter >= 0 && opts.FlushJitter <= 1, "FlushJitter is not between 0 and 1: %g", opts.FlushJitter)
+
+	for _, level := range logrus.AllLevels {
+		if rate, ok := opts.SampleRates[level]; ok {
+			check(rate >= 0 && rate <= 1, "sample rate of %s is not between 0 and 1: %g", level, rate)
+		}
+	}
+
+	if w := opts.Watermarks; w != nil {
+		check(w.High >= 0 && w.High <= 1, "Watermarks.High is not between 0 and 1: %g", w.High)
+		check(w.Low >= 0 && w.Low <= 1, "Watermarks.Low is not between 0 and 1: %g", w.Low)
+		check(w.High == 0 || w.Low <= w.High, "Watermarks.Low is above Watermarks.High")
+	}
+
+	if o := opts.Offline; o != nil {
+		check(o.Threshold >= 0, "Offline.Threshold is negative: %d", o.Threshold)
+		check(o.ProbeInterval >= 0, "Offline.ProbeInterval is negative: %s", o.ProbeInterval)
+		check(o.MaxBytes >= 0, "Offline.MaxBytes is negative: %d", o.MaxBytes)
+		check(o.DrainInterval >= 0, "Offline.DrainInterval is negative: %s", o.DrainInterval)
+	}
+
+	check(opts.OversizePolicy >= OversizeTruncate && opts.OversizePolicy <= OversizeReject, "unknown OversizePolicy %d", opts.OversizePolicy)
+	check(opts.OverflowPolicy >= OverflowBlock && opts.OverflowPolicy <= OverflowDropOldest, "unknown OverflowPolicy %d", opts.OverflowPolicy)
+	check(opts.Protocol >= ProtocolHTTP && opts.Protocol <= ProtocolAgent, "unknown Protocol %d", opts.Protocol)
+	check(opts.BodyFormat >= JSONArray && opts.BodyFormat <= PlainText, "unknown BodyFormat %d", opts.BodyFormat)
+
+	if opts.PostURL != "" {
+		u, err := url.Parse(opts.PostURL)
+		switch {
+		case err != nil:
+			check(false, "PostURL is malformed: %v", err)
+		case u.Scheme != "http" && u.Scheme != "https":
+			check(false, "PostURL is not an http or https URL: %s", opts.PostURL)
+		case u.Host == "":
+			check(false, "PostURL has no host: %s", opts.PostURL)
+		}
+	}
+
+	for _, tag := range opts.Tags {
+		check(tag != "" && !strings.ContainsAny(tag, ", "), "tag %q is empty or contains a comma or a space", tag)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidOpts, strings.Join(problems, "; "))
+}
+
+// needsAPIKey tells if apiKey must be set for opts to deliver anything:
+// Sink, the agent and APIKeyFunc don't need it.
+func (opts Opts) needsAPIKey() bool {
+	return opts.Sink == nil && opts.Protocol != ProtocolAgent && opts.APIKeyFunc == nil && opts.PostURL == ""
+}
+
+// NewWithError is like New, but returns an error if opts are not valid, see
+// Opts.Validate, or if apiKey is empty when sending to the Datadog intake.
+func NewWithError(apiKey string, opts Opts) (*Hook, error) {
+	err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == "" && opts.needsAPIKey() {
+		return nil, fmt.Errorf("%w: the API key is empty", ErrInvalidOpts)
+	}
+
+	return New(apiKey, opts), nil
+}
+
+// NewWithOptionsError is like NewWithOptions, but returns an error if the
+// resulting Opts are not valid, see NewWithError.
+func NewWithOptionsError(apiKey string, options ...Option) (*Hook, error) {
+	var opts Opts
+	for _, option := range options {
+		option(&opts)
+	}
+
+	return NewWithError(apiKey, opts)
+}
@@ -0,0 +1,122 @@
+package dogrus_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"github.com/sirupsen/logrus"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts dogrus.Opts
+		want []string
+	}{
+		{"defaults", dogrus.Opts{}, nil},
+		{
+			"valid",
+			dogrus.Opts{
+				FlushPeriod: time.Second,
+				FlushJitter: 1,
+				PostURL:     "https://http-intake.logs.datadoghq.eu/api/v2/logs",
+				SampleRates: map[logrus.Level]float64{logrus.DebugLevel: 0.1},
+				Watermarks:  &dogrus.Watermarks{High: 0.9, Low: 0.5},
+				Tags:        []string{"env:prod"},
+			},
+			nil,
+		},
+		{
+			"negative values",
+			dogrus.Opts{FlushPeriod: -time.Second, QueueSize: -1},
+			[]string{"FlushPeriod is negative: -1s", "QueueSize is negative: -1"},
+		},
+		{
+			"out of range",
+			dogrus.Opts{
+				FlushJitter: 1.5,
+				SampleRates: map[logrus.Level]float64{logrus.InfoLevel: 2},
+				Watermarks:  &dogrus.Watermarks{High: 0.5, Low: 0.8},
+			},
+			[]string{
+				"FlushJitter is not between 0 and 1: 1.5",
+				"sample rate of info is not between 0 and 1: 2",
+				"Watermarks.Low is above Watermarks.High",
+			},
+		},
+		{
+			"unknown enums",
+			dogrus.Opts{OverflowPolicy: 7, BodyFormat: 9},
+			[]string{"unknown OverflowPolicy 7", "unknown BodyFormat 9"},
+		},
+		{"PostURL scheme", dogrus.Opts{PostURL: "intake.example.com/logs"}, []string{"PostURL is not an http or https URL"}},
+		{"PostURL host", dogrus.Opts{PostURL: "https:///logs"}, []string{"PostURL has no host"}},
+		{"PostURL malformed", dogrus.Opts{PostURL: "http://[::1"}, []string{"PostURL is malformed"}},
+		{"tags", dogrus.Opts{Tags: []string{"env:prod", "team:a,b", ""}}, []string{`tag "team:a,b"`, `tag ""`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("got %v, want nil", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, dogrus.ErrInvalidOpts) {
+				t.Fatalf("got %v, want an error wrapping ErrInvalidOpts", err)
+			}
+
+			// every problem is listed, not just the first one
+			for _, problem := range tt.want {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("%q doesn't mention %q", err, problem)
+				}
+			}
+		})
+	}
+}
+
+func TestNewWithError(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+		opts   dogrus.Opts
+		valid  bool
+	}{
+		{"valid", "key", dogrus.Opts{}, true},
+		{"invalid", "key", dogrus.Opts{FlushPeriod: -time.Second}, false},
+		{"empty API key", "", dogrus.Opts{}, false},
+		{"empty API key with a Sink", "", dogrus.Opts{Sink: dogrus.NewMemorySink()}, true},
+		{"empty API key with the agent", "", dogrus.Opts{Protocol: dogrus.ProtocolAgent}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := dogrus.NewWithError(tt.apiKey, tt.opts)
+			if tt.valid {
+				if err != nil || d == nil {
+					t.Fatalf("got %v, want a hook", err)
+				}
+				d.Close()
+				return
+			}
+
+			if d != nil || !errors.Is(err, dogrus.ErrInvalidOpts) {
+				t.Fatalf("got %v, %v, want an error wrapping ErrInvalidOpts", d, err)
+			}
+		})
+	}
+}
+
+func TestNewWithOptionsError(t *testing.T) {
+	_, err := dogrus.NewWithOptionsError("key", dogrus.WithPostURL("ftp://intake"))
+	if !errors.Is(err, dogrus.ErrInvalidOpts) || !strings.Contains(err.Error(), "PostURL") {
+		t.Fatalf("got %v, want the PostURL reported", err)
+	}
+}
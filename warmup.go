@@ -0,0 +1,55 @@
+package dogrus
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// warmUp opens a connection to the intake ahead of the first batch, so it
+// doesn't pay for DNS resolution and TLS handshake. Failures are ignored,
+// the connection is simply opened again by the first request.
+func (c *Client) warmUp() {
+	c.connect()
+}
+
+// connect opens a connection to the intake, kept for the next requests,
+// returning an error if it's unreachable.
+func (c *Client) connect() error {
+	if c.opts.Sink != nil {
+		return nil
+	}
+
+	if c.opts.Protocol != ProtocolHTTP {
+		c.connMu.Lock()
+		defer c.connMu.Unlock()
+
+		if c.conn == nil {
+			conn, err := c.dial()
+			if err != nil {
+				return err
+			}
+			c.conn = conn
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.opts.RequestTimeout)
+	defer cancel()
+
+	// the response doesn't matter, the connection is kept for the next
+	// requests
+	req, err := http.NewRequestWithContext(ctx, "HEAD", c.postURL, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	return nil
+}
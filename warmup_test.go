@@ -0,0 +1,64 @@
+package dogrus_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Pitasi/dogrus"
+)
+
+func TestWarmUpReusesConnection(t *testing.T) {
+	var conns int32
+	var mu sync.Mutex
+	var requests []string
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		mu.Unlock()
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	c := dogrus.NewClient("key", dogrus.Opts{
+		PostURL:     srv.URL + "/v1/input",
+		FlushPeriod: time.Hour,
+		BodyFormat:  dogrus.PlainText,
+		Service:     "api",
+		Hostname:    "web-1",
+		WarmUp:      true,
+	})
+	defer c.Close()
+
+	waitFor(t, "the warm-up request", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(requests) == 1
+	})
+
+	c.Enqueue([]byte("INFO hello"))
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the warm-up goes to the same URL as the batches, query included
+	want := []string{"HEAD /v1/input?hostname=web-1&service=api", "POST /v1/input?hostname=web-1&service=api"}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != len(want) || requests[0] != want[0] || requests[1] != want[1] {
+		t.Fatalf("requests are %q, want %q", requests, want)
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("opened %d connections, the first flush didn't reuse the warm one", n)
+	}
+}
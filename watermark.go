@@ -0,0 +1,61 @@
+package dogrus
+
+import "sync/atomic"
+
+// Watermarks configures callbacks telling when the queue is filling up, so
+// the application can shed its own load or raise its log level before
+// entries are dropped or Fire blocks.
+type Watermarks struct {
+	// High is the fraction of QueueSize that, once reached, calls OnHigh.
+	// By default is 0.8.
+	High float64
+
+	// Low is the fraction of QueueSize that, after High has been reached,
+	// calls OnLow once the queue drains below it.
+	// By default is half of High.
+	Low float64
+
+	// OnHigh is called with the current queue usage when it reaches High.
+	OnHigh func(usage float64)
+
+	// OnLow is called with the current queue usage when it goes back to
+	// Low.
+	OnLow func(usage float64)
+}
+
+// withDefaults returns w with the default values filled in.
+func (w Watermarks) withDefaults() Watermarks {
+	if w.High == 0 {
+		w.High = 0.8
+	}
+
+	if w.Low == 0 {
+		w.Low = w.High / 2
+	}
+
+	return w
+}
+
+// checkPressure calls the Watermarks callbacks if the queue usage crossed
+// one of them. The callbacks run on the goroutine that changed the queue,
+// they must be quick.
+func (c *Client) checkPressure() {
+	w := c.opts.Watermarks
+	if w == nil {
+		return
+	}
+
+	usage := float64(len(c.queue)) / float64(cap(c.queue))
+
+	switch {
+	case usage >= w.High && atomic.CompareAndSwapInt32(&c.pressured, 0, 1):
+		if w.OnHigh != nil {
+			w.OnHigh(usage)
+		}
+
+	case usage <= w.Low && atomic.CompareAndSwapInt32(&c.pressured, 1, 0):
+		if w.OnLow != nil {
+			w.OnLow(usage)
+		}
+	}
+}
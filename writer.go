@@ -0,0 +1,201 @@
+package dogrus
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// LineOpts are the attributes attached by a LineWriter to every line.
+// All options can be left empty.
+type LineOpts struct {
+	// Source is the technology the lines come from, sent as the ddsource
+	// attribute, such as nginx or the name of a subprocess.
+	Source string
+
+	// Service overrides the service set by the client Opts.
+	Service string
+
+	// Host overrides the hostname set by the client Opts.
+	Host string
+
+	// Level is the level of the entries.
+	// By default is info.
+	Level string
+}
+
+// LineWriter is an io.Writer turning every line written to it into a log
+// entry enqueued in a Client. It's useful to capture the output of
+// subprocesses or of libraries that only write to an io.Writer:
+//
+//	cmd.Stderr = client.LineWriter(dogrus.LineOpts{Source: "ffmpeg"})
+//
+// Lines can be split across multiple calls to Write, an incomplete line is
+// kept until the rest of it is written or Flush is called.
+// It's safe for concurrent use.
+type LineWriter struct {
+	client *Client
+	attrs  map[string]string
+
+	mu      sync.Mutex
+	partial []byte
+}
+
+// LineWriter creates a LineWriter sending lines through the client.
+// The attributes returned by Metadata are added to every entry, along
+// with the ones in opts.
+func (c *Client) LineWriter(opts LineOpts) *LineWriter {
+	if opts.Level == "" {
+		opts.Level = "info"
+	}
+
+	attrs := c.Metadata()
+	attrs["level"] = opts.Level
+
+	if opts.Source != "" {
+		attrs[SourceKey] = opts.Source
+	}
+
+	if opts.Service != "" {
+		attrs[ServiceKey] = opts.Service
+	}
+
+	if opts.Host != "" {
+		attrs[HostnameKey] = opts.Host
+	}
+
+	return &LineWriter{
+		client: c,
+		attrs:  attrs,
+	}
+}
+
+// Write implements io.Writer. Empty lines are skipped.
+// If a line can't be enqueued, Write returns the number of bytes of p up to
+// the end of the last line enqueued, along with the error, and the rest of p
+// is not kept.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// the incomplete line of the previous calls, followed by p
+	prev := len(w.partial)
+	buf := append(w.partial, p...)
+	w.partial = nil
+
+	start := 0
+	for {
+		i := bytes.IndexByte(buf[start:], '\n')
+		if i < 0 {
+			break
+		}
+
+		err := w.send(buf[start : start+i])
+		if err != nil {
+			return consumed(start, prev), err
+		}
+		start += i + 1
+	}
+
+	// don't let a line without newline grow forever
+	if len(buf)-start >= w.client.opts.MaxEntryBytes {
+		err := w.send(buf[start:])
+		if err != nil {
+			return consumed(start, prev), err
+		}
+		start = len(buf)
+	}
+
+	if start < len(buf) {
+		w.partial = buf[start:]
+	}
+
+	return len(p), nil
+}
+
+// consumed returns how many bytes of p have been handled when the line at
+// start fails, in a buffer made of the prev bytes left by the previous calls
+// followed by p. Those bytes were already reported as written.
+func consumed(start, prev int) int {
+	if start < prev {
+		return 0
+	}
+
+	return start - prev
+}
+
+// Flush sends the incomplete line written so far, if any.
+// It doesn't flush the client queue.
+func (w *LineWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	line := w.partial
+	w.partial = nil
+
+	return w.send(line)
+}
+
+// send enqueues line as the message of an entry. Lines too long for
+// MaxEntryBytes, once wrapped with the attributes, are split into several
+// entries.
+func (w *LineWriter) send(line []byte) error {
+	line = bytes.TrimRight(line, "\r")
+	if len(bytes.TrimSpace(line)) == 0 {
+		return nil
+	}
+
+	timestamp := w.client.opts.Clock.Now().Format(time.RFC3339Nano)
+	max := w.client.opts.MaxEntryBytes
+
+	for len(line) > 0 {
+		n := len(line)
+
+		for {
+			data, err := w.encode(timestamp, line[:n])
+			if err != nil {
+				return err
+			}
+
+			excess := len(data) - max
+			if excess <= 0 {
+				err = w.client.Enqueue(data)
+				if err != nil {
+					return err
+				}
+				break
+			}
+
+			// escaping makes the message at least as long as the line, so
+			// cutting the excess from the line is enough to fit
+			n -= excess
+			for n > 0 && !utf8.RuneStart(line[n]) {
+				n--
+			}
+
+			if n <= 0 {
+				// there isn't room even for a character of the line, the
+				// entry is rejected
+				return w.client.Enqueue(data)
+			}
+		}
+
+		line = line[n:]
+	}
+
+	return nil
+}
+
+// encode returns the entry with message as its message.
+func (w *LineWriter) encode(timestamp string, message []byte) ([]byte, error) {
+	entry := make(map[string]string, len(w.attrs)+2)
+	for k, v := range w.attrs {
+		entry[k] = v
+	}
+	entry["timestamp"] = timestamp
+	entry["message"] = string(message)
+
+	return json.Marshal(entry)
+}
@@ -0,0 +1,103 @@
+package dogrus
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineWriterSplitsLongLines(t *testing.T) {
+	sink := NewMemorySink()
+	c := NewClient("key", Opts{Sink: sink, MaxEntryBytes: 300, Service: "checkout"})
+
+	var rejected error
+	c.opts.OnError = func(err error, _ [][]byte) { rejected = err }
+
+	w := c.LineWriter(LineOpts{Source: "ffmpeg"})
+
+	// a full line, and a partial one forced out by its size, both with
+	// characters needing escaping or multiple bytes
+	long := strings.Repeat(`a"é`, 150)
+	if _, err := w.Write([]byte(long + "\n" + long)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rejected != nil {
+		t.Fatal(rejected)
+	}
+
+	var messages strings.Builder
+	for _, entry := range sink.Entries() {
+		if len(entry) > 300 {
+			t.Errorf("entry of %d bytes", len(entry))
+		}
+
+		var fields map[string]string
+		if err := json.Unmarshal(entry, &fields); err != nil {
+			t.Fatal(err)
+		}
+		if fields[SourceKey] != "ffmpeg" || fields[ServiceKey] != "checkout" {
+			t.Errorf("entry without attributes: %s", entry)
+		}
+		messages.WriteString(fields["message"])
+	}
+
+	if messages.String() != long+long {
+		t.Errorf("lines have not been split losslessly")
+	}
+}
+
+func TestLineWriterPartialFailure(t *testing.T) {
+	sink := NewMemorySink()
+	clock := &fixedClock{Clock: SystemClock, now: time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)}
+	c := NewClient("key", Opts{Sink: sink, Clock: clock})
+	w := c.LineWriter(LineOpts{})
+
+	// only two bytes of message fit, a control character escapes to six
+	ok, err := w.encode(clock.now.Format(time.RFC3339Nano), []byte("ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.opts.MaxEntryBytes = len(ok)
+
+	writes := []struct {
+		p   string
+		n   int
+		err error
+	}{
+		{"ok\n\x01\nok\n", 3, ErrEntryTooLarge},
+		{"o", 1, nil},
+		{"k\n\x01\n", 2, ErrEntryTooLarge},
+		// the failed line began in the previous call, it's discarded
+		{"\x01", 1, nil},
+		{"\nok\n", 0, ErrEntryTooLarge},
+		{"ok\n", 3, nil},
+	}
+	for i, wr := range writes {
+		n, err := w.Write([]byte(wr.p))
+		if n != wr.n || err != wr.err {
+			t.Errorf("write %d returned %d, %v, want %d, %v", i, n, err, wr.n, wr.err)
+		}
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := sink.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	for _, e := range entries {
+		if e["message"] != "ok" {
+			t.Errorf("unexpected entry %v", e)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Package zapdd provides a zapcore.Core sending entries to Datadog through a
+// dogrus client.
+//
+// Entries go through the same queue, batching and retry machinery of the
+// dogrus hook, so zap-based services don't need a Datadog agent just for
+// logs:
+//
+//	client := dogrus.NewClient(apiKey, dogrus.Opts{})
+//	logger := zap.New(zapdd.NewCore(client, zapcore.InfoLevel))
+package zapdd
+
+import (
+	"time"
+
+	"github.com/Pitasi/dogrus"
+	"go.uber.org/zap/zapcore"
+)
+
+// EncoderConfig returns the configuration of the JSON encoder used by the
+// Core, mapping zap keys to Datadog standard attributes.
+// The caller is left out, since zap encodes it as a single value: Core adds
+// its file and line as the logger.file and logger.line attributes.
+func EncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "level",
+		NameKey:        dogrus.LoggerNameKey,
+		FunctionKey:    dogrus.LoggerMethodNameKey,
+		MessageKey:     "message",
+		StacktraceKey:  dogrus.ErrorStackKey,
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    encodeLevel,
+		EncodeTime:     zapcore.TimeEncoderOfLayout(time.RFC3339Nano),
+		EncodeDuration: zapcore.StringDurationEncoder,
+	}
+}
+
+// encodeLevel encodes levels with the names used by logrus, which are
+// understood by Datadog.
+func encodeLevel(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	switch l {
+	case zapcore.WarnLevel:
+		enc.AppendString("warning")
+	case zapcore.DPanicLevel:
+		enc.AppendString("panic")
+	default:
+		enc.AppendString(l.String())
+	}
+}
+
+// Core is a zapcore.Core enqueuing entries, encoded as JSON objects, in a
+// dogrus client.
+type Core struct {
+	zapcore.LevelEnabler
+
+	enc    zapcore.Encoder
+	client *dogrus.Client
+}
+
+// NewCore creates a Core sending the entries enabled by enab through
+// client. The attributes returned by client.Metadata are added to every
+// entry.
+func NewCore(client *dogrus.Client, enab zapcore.LevelEnabler) *Core {
+	enc := zapcore.NewJSONEncoder(EncoderConfig())
+	for k, v := range client.Metadata() {
+		enc.AddString(k, v)
+	}
+
+	return &Core{
+		LevelEnabler: enab,
+		enc:          enc,
+		client:       client,
+	}
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	return &Core{
+		LevelEnabler: c.LevelEnabler,
+		enc:          enc,
+		client:       c.client,
+	}
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core. Entries above ErrorLevel are flushed right
+// away, like zap's own cores sync them, since the process is about to panic
+// or exit.
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Caller.Defined {
+		// the fields belong to the caller, don't append to their array
+		fields = append(fields[:len(fields):len(fields)],
+			zapcore.Field{Key: dogrus.LoggerFileKey, Type: zapcore.StringType, String: ent.Caller.File},
+			zapcore.Field{Key: dogrus.LoggerLineKey, Type: zapcore.Int64Type, Integer: int64(ent.Caller.Line)},
+		)
+	}
+
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+
+	// the buffer goes back to zap pool once freed
+	entry := make([]byte, buf.Len())
+	copy(entry, buf.Bytes())
+	buf.Free()
+
+	err = c.client.Enqueue(entry)
+	if err != nil {
+		return err
+	}
+
+	// zap panics or exits right after writing entries above ErrorLevel
+	if ent.Level > zapcore.ErrorLevel {
+		return c.client.Flush()
+	}
+
+	return nil
+}
+
+// Sync implements zapcore.Core, flushing the client queue.
+func (c *Core) Sync() error {
+	return c.client.Flush()
+}
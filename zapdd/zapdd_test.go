@@ -0,0 +1,87 @@
+package zapdd
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/Pitasi/dogrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// countingSink counts the entries it receives.
+type countingSink struct {
+	mu      sync.Mutex
+	entries int
+}
+
+func (s *countingSink) Send(_ context.Context, batch [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries += len(batch)
+	return nil
+}
+
+func (s *countingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries
+}
+
+func TestCoreFlushesPanics(t *testing.T) {
+	sink := &countingSink{}
+	client := dogrus.NewClient("key", dogrus.Opts{Sink: sink})
+	defer client.Close()
+
+	logger := zap.New(NewCore(client, zapcore.DebugLevel))
+
+	logger.Error("queued")
+	if n := sink.count(); n != 0 {
+		t.Fatalf("%d entries sent before the flush period", n)
+	}
+
+	func() {
+		defer func() { recover() }()
+		logger.Panic("flushed")
+	}()
+
+	if n := sink.count(); n != 2 {
+		t.Fatalf("%d entries sent after Panic, want 2", n)
+	}
+}
+
+func TestCoreCaller(t *testing.T) {
+	sink := dogrus.NewMemorySink()
+	client := dogrus.NewClient("key", dogrus.Opts{Sink: sink})
+
+	logger := zap.New(NewCore(client, zapcore.DebugLevel), zap.AddCaller())
+	_, file, line, _ := runtime.Caller(0)
+	logger.Info("hello", zap.String("user", "alice"))
+	zap.New(NewCore(client, zapcore.DebugLevel)).Info("no caller")
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := sink.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+
+	e := entries[0]
+	if e[dogrus.LoggerFileKey] != file || e[dogrus.LoggerLineKey] != float64(line+1) {
+		t.Errorf("caller is %v:%v, want %s:%d", e[dogrus.LoggerFileKey], e[dogrus.LoggerLineKey], file, line+1)
+	}
+	if e["user"] != "alice" || e["message"] != "hello" {
+		t.Errorf("entry is %v", e)
+	}
+
+	if _, ok := entries[1][dogrus.LoggerFileKey]; ok {
+		t.Errorf("entry without caller has %s", dogrus.LoggerFileKey)
+	}
+}
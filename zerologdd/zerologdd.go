@@ -0,0 +1,103 @@
+// Package zerologdd provides an io.Writer sending zerolog output to Datadog
+// through a dogrus client.
+//
+// Each JSON line written by zerolog becomes a log entry, enriched with the
+// client metadata and sent through the same queue, batching and retry
+// machinery of the dogrus hook:
+//
+//	client := dogrus.NewClient(apiKey, dogrus.Opts{})
+//	logger := zerolog.New(zerologdd.NewWriter(client)).With().Timestamp().Logger()
+package zerologdd
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/Pitasi/dogrus"
+)
+
+// zerolog default key for the timestamp, which is not recognized by Datadog.
+const zerologTimeKey = "time"
+
+// Writer is an io.Writer turning every JSON line written to it into a log
+// entry enqueued in a dogrus client.
+// Lines that are not JSON objects are sent as the message of an entry.
+type Writer struct {
+	client   *dogrus.Client
+	metadata map[string]json.RawMessage
+}
+
+// NewWriter creates a Writer sending entries through client.
+// The attributes returned by client.Metadata are added to every entry,
+// unless it already has them.
+func NewWriter(client *dogrus.Client) *Writer {
+	metadata := make(map[string]json.RawMessage)
+	for k, v := range client.Metadata() {
+		metadata[k], _ = json.Marshal(v)
+	}
+
+	return &Writer{
+		client:   client,
+		metadata: metadata,
+	}
+}
+
+// Write implements io.Writer. Zerolog writes a single line per call, but
+// multiple lines are handled too. If a line can't be enqueued, Write returns
+// the number of bytes of p up to the end of the last line enqueued.
+func (w *Writer) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		end := len(p)
+		if i := bytes.IndexByte(p[n:], '\n'); i >= 0 {
+			end = n + i + 1
+		}
+
+		line := bytes.TrimSpace(p[n:end])
+		if len(line) > 0 {
+			entry, err := w.entry(line)
+			if err != nil {
+				return n, err
+			}
+
+			err = w.client.Enqueue(entry)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		n = end
+	}
+
+	return n, nil
+}
+
+// entry converts a line written by zerolog into a log entry.
+func (w *Writer) entry(line []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+
+	// a null line decodes without error into a nil map
+	err := json.Unmarshal(line, &fields)
+	if err != nil || fields == nil {
+		message, err := json.Marshal(string(line))
+		if err != nil {
+			return nil, err
+		}
+		fields = map[string]json.RawMessage{"message": message}
+	}
+
+	if t, ok := fields[zerologTimeKey]; ok {
+		if _, ok := fields["timestamp"]; !ok {
+			fields["timestamp"] = t
+			delete(fields, zerologTimeKey)
+		}
+	}
+
+	for k, v := range w.metadata {
+		if _, ok := fields[k]; !ok {
+			fields[k] = v
+		}
+	}
+
+	return json.Marshal(fields)
+}
@@ -0,0 +1,74 @@
+package zerologdd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Pitasi/dogrus"
+)
+
+func TestWriterLines(t *testing.T) {
+	sink := dogrus.NewMemorySink()
+	client := dogrus.NewClient("key", dogrus.Opts{Sink: sink, Service: "checkout"})
+
+	w := NewWriter(client)
+	lines := "null\n" + `{"level":"info","message":"ok","time":"2024-01-01T00:00:00Z"}` + "\nnot json\n[1,2]\n"
+	if _, err := w.Write([]byte(lines)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := sink.Entries()
+	if len(entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(entries))
+	}
+
+	want := []string{"null", "ok", "not json", "[1,2]"}
+	for i, entry := range entries {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry, &fields); err != nil {
+			t.Fatal(err)
+		}
+
+		if fields["message"] != want[i] || fields["service"] != "checkout" {
+			t.Errorf("entry %d is %s", i, entry)
+		}
+	}
+}
+
+func TestWriterPartialFailure(t *testing.T) {
+	sink := dogrus.NewMemorySink()
+	client := dogrus.NewClient("key", dogrus.Opts{Sink: sink, MaxEntryBytes: 200})
+
+	w := NewWriter(client)
+	first := `{"message":"first"}` + "\n"
+	long := `{"message":"` + strings.Repeat("a", 300) + `"}` + "\n"
+	p := first + long + `{"message":"last"}` + "\n"
+
+	n, err := w.Write([]byte(p))
+	if n != len(first) || err != dogrus.ErrEntryTooLarge {
+		t.Fatalf("Write returned %d, %v, want %d, ErrEntryTooLarge", n, err, len(first))
+	}
+
+	// the caller writes the rest again, without the line that failed
+	n, err = w.Write([]byte(p[len(first)+len(long):]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := sink.Decoded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0]["message"] != "first" || entries[1]["message"] != "last" {
+		t.Errorf("entries are %v, want first and last once", entries)
+	}
+}